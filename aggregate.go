@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math"
+)
+
+// aggregateChannelID stands for the combined total of all channels in latestCount, the history
+// and notifications. Real channel IDs start with UC so it cannot collide.
+const aggregateChannelID = "aggregate"
+
+const defaultAggregateLabel = "All channels"
+
+// aggregateChannel is the pseudo channel notified about when aggregate is enabled
+func aggregateChannel() ChannelConfig {
+	label := currentConfig().AggregateLabel
+	if label == "" {
+		label = defaultAggregateLabel
+	}
+	return ChannelConfig{ChannelID: aggregateChannelID, Label: label}
+}
+
+// updateAggregate tracks the sum of the subscriber counts in stats like a single channel.
+// Channels hiding their count are left out of the sum, so the total jumps when one of them
+// starts or stops hiding it.
+func updateAggregate(ctx context.Context, stats []ChannelStats) {
+	var total uint64
+	for _, s := range stats {
+		if s.HiddenSubscriberCount {
+			continue
+		}
+		if s.SubscriberCount > math.MaxUint64-total {
+			total = math.MaxUint64
+			break
+		}
+		total += s.SubscriberCount
+	}
+
+	slog.DebugContext(ctx, "Aggregated subscriber count", "event", "poll", "channels", len(stats), "subscriber_count", total)
+	updateSubscriberCount(ctx, aggregateChannel(), total)
+	recordHistory(ChannelStats{ChannelID: aggregateChannelID, SubscriberCount: total})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+func dropAlertsConfigured() bool {
+	return len(currentConfig().AlertChatIDs) > 0 || currentConfig().AlertWebhookURL != ""
+}
+
+// checkDrop raises a drop alert when the count fell by at least drop_alert_threshold
+// (default 1) since the previous poll. It is independent of min_change and notify_cooldown.
+func checkDrop(ctx context.Context, channel ChannelConfig, previous, current uint64) {
+	if !dropAlertsConfigured() {
+		return
+	}
+	threshold := currentConfig().DropThreshold
+	if threshold == 0 {
+		threshold = 1
+	}
+	if previous-current < threshold {
+		return
+	}
+	sendDropAlert(ctx, channel, previous, current)
+}
+
+// sendDropAlert routes a subscriber drop to alert_chat_ids and alert_webhook_url,
+// separately from the routine growth notifications
+func sendDropAlert(ctx context.Context, channel ChannelConfig, previous, current uint64) {
+	slog.WarnContext(ctx, "Subscriber drop", "event", "alert", "channel_id", channel.ChannelID, "previous_count", previous, "subscriber_count", current)
+
+	text := fmt.Sprintf("⚠️ %s lost %s subscribers: %s → %s", channel.Name(), formatCount(previous-current), formatCount(previous), formatCount(current))
+	if len(currentConfig().AlertChatIDs) > 0 {
+		sendTelegramMessageTo(ctx, currentConfig().AlertChatIDs, escapeMarkdownV2(text), "MarkdownV2")
+	}
+
+	if currentConfig().AlertWebhookURL != "" {
+		now := time.Now().UTC()
+		payload := map[string]interface{}{
+			"type":             "drop_alert",
+			"channel_id":       channel.ChannelID,
+			"channel_title":    channel.Name(),
+			"previous_count":   previous,
+			"subscriber_count": current,
+			"delta":            countDelta(previous, current),
+			"message":          text,
+			"event_id":         eventID(Notification{ChannelID: channel.ChannelID, Metric: "drop_alert", PreviousCount: previous, Count: current}, now),
+			"sent_at":          now.Format(time.RFC3339),
+		}
+		if pollID := pollIDFrom(ctx); pollID != "" {
+			payload["poll_id"] = pollID
+		}
+		body, _ := json.Marshal(payload)
+		if err := postWebhook(ctx, WebhookConfig{URL: currentConfig().AlertWebhookURL}, body); err != nil {
+			slog.ErrorContext(ctx, "Error sending drop alert", "event", "alert", "platform", "webhook", "url", currentConfig().AlertWebhookURL, "error", err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestDropAlertWebhookCarriesPollID(t *testing.T) {
+	resetPollState(t)
+	cfg := useConfig(t, testConfigYAML+"alert_webhook_url: https://example.com/alerts\n")
+
+	ctx := withPollID(context.Background(), "poll-1")
+	sendDropAlert(ctx, cfg.Channels[0], 110, 100)
+
+	deliveriesMutex.Lock()
+	recent := recentDeliveries()
+	deliveriesMutex.Unlock()
+	if len(recent) != 1 {
+		t.Fatalf("%d deliveries recorded, want the drop alert only", len(recent))
+	}
+	var payload struct {
+		Type   string `json:"type"`
+		PollID string `json:"poll_id"`
+	}
+	if err := json.Unmarshal([]byte(recent[0].Payload), &payload); err != nil {
+		t.Fatalf("decoding drop alert %q: %v", recent[0].Payload, err)
+	}
+	if payload.Type != "drop_alert" || payload.PollID != "poll-1" {
+		t.Errorf("drop alert payload %s, want type drop_alert and poll_id poll-1", recent[0].Payload)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomicReplaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte(`{"old":true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte(`{"new":true}`), 0600); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	assertFileContent(t, path, `{"new":true}`)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("file mode is %v, want 0600", perm)
+	}
+	assertNoTempFiles(t, filepath.Dir(path))
+}
+
+// A crash during a write leaves a partial temporary file behind, never a partial state file
+func TestWriteFileAtomicIgnoresLeftoverTempFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(path, []byte(`{"old":true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	leftover := filepath.Join(dir, ".state.json.tmp123")
+	if err := os.WriteFile(leftover, []byte(`{"ne`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assertFileContent(t, path, `{"old":true}`)
+
+	if err := writeFileAtomic(path, []byte(`{"new":true}`), 0644); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	assertFileContent(t, path, `{"new":true}`)
+}
+
+func assertFileContent(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("%s contains %q, want %q", filepath.Base(path), data, want)
+	}
+}
+
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, ".*.tmp*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) > 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
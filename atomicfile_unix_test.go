@@ -0,0 +1,44 @@
+//go:build unix
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// A write cut short by the file size limit fails and leaves the original file untouched
+func TestWriteFileAtomicPartialWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(path, []byte(`{"old":true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes beyond RLIMIT_FSIZE stop after the limit and fail with EFBIG instead of raising SIGXFSZ
+	signal.Ignore(syscall.SIGXFSZ)
+	defer signal.Reset(syscall.SIGXFSZ)
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_FSIZE, &limit); err != nil {
+		t.Fatal(err)
+	}
+	small := limit
+	small.Cur = 1024
+	if err := syscall.Setrlimit(syscall.RLIMIT_FSIZE, &small); err != nil {
+		t.Skipf("cannot lower RLIMIT_FSIZE: %v", err)
+	}
+	err := writeFileAtomic(path, bytes.Repeat([]byte("x"), 4096), 0644)
+	if err := syscall.Setrlimit(syscall.RLIMIT_FSIZE, &limit); err != nil {
+		t.Fatal(err)
+	}
+
+	if err == nil {
+		t.Fatal("writeFileAtomic succeeded beyond the file size limit")
+	}
+	assertFileContent(t, path, `{"old":true}`)
+	assertNoTempFiles(t, dir)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+// isInvalidGrant reports whether err means the refresh token was revoked or expired
+func isInvalidGrant(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	return errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant"
+}
+
+// isTokenRejected reports whether err means Google refused the OAuth token, either when
+// refreshing it or when calling the API with it
+func isTokenRejected(err error) bool {
+	var apiErr *googleapi.Error
+	return isInvalidGrant(err) || errors.As(err, &apiErr) && apiErr.Code == http.StatusUnauthorized
+}
+
+const (
+	defaultOAuthExchangeAttempts = 3
+	oauthExchangeInitialDelay    = 500 * time.Millisecond
+	// Tokens are refreshed this long before they expire unless token_refresh_skew is set
+	defaultTokenRefreshSkew = time.Minute
+)
+
+// tokenNeedsRefresh reports whether tok expires within skew of now. A token without an expiry
+// never needs refreshing.
+func tokenNeedsRefresh(tok *oauth2.Token, now time.Time, skew time.Duration) bool {
+	return !tok.Expiry.IsZero() && tok.Expiry.Before(now.Add(skew))
+}
+
+// exchangeWithRetry trades the authorization code for a token, retrying transient failures
+// up to oauth_exchange_attempts times
+func exchangeWithRetry(ctx context.Context, code, verifier string) (*oauth2.Token, error) {
+	attempts := currentConfig().OAuthExchangeAttempts
+	if attempts <= 0 {
+		attempts = defaultOAuthExchangeAttempts
+	}
+
+	delay := oauthExchangeInitialDelay
+	for attempt := 1; ; attempt++ {
+		tok, err := oauthConfig.Exchange(oauthContext(ctx), code, oauth2.VerifierOption(verifier))
+		if err == nil || attempt == attempts || !isTransientOAuthError(err) {
+			return tok, err
+		}
+
+		slog.Warn("Token exchange failed, retrying", "event", "oauth", "attempt", attempt, "wait", delay.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// isTransientOAuthError reports whether err may go away by retrying: network errors, timeouts
+// and 5xx responses. Errors such as invalid_grant or invalid_request never do.
+func isTransientOAuthError(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return retrieveErr.Response != nil && retrieveErr.Response.StatusCode >= 500
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// validateToken makes one cheap Channels.List call with the stored token at startup. A token
+// Google rejects is cleared right away, so the login prompt and notify_auth_failure do not
+// wait for the first poll. Other failures, such as a network outage, are only logged.
+func validateToken(ctx context.Context) {
+	fetcher, err := newFetcher(ctx)
+	if err == nil {
+		err = probeFetcher(ctx, fetcher)
+	}
+	if err == nil {
+		slog.Info("OAuth token is valid", "event", "oauth")
+		return
+	}
+	if !isTokenRejected(err) {
+		slog.Warn("Unable to validate OAuth token", "event", "oauth", "error", err)
+		return
+	}
+
+	tokenMutex.Lock()
+	defer tokenMutex.Unlock()
+	// newFetcher already cleared a token whose refresh failed
+	if token != nil {
+		clearRevokedToken(err)
+	}
+}
+
+// probeFetcher reads the first configured channel, resolving its handle when the ID is unknown
+func probeFetcher(ctx context.Context, fetcher StatsFetcher) error {
+	channels := currentConfig().Channels
+	if len(channels) == 0 {
+		return nil
+	}
+	if channels[0].ChannelID == "" {
+		_, err := fetcher.ResolveChannelHandle(ctx, channels[0].Handle)
+		return err
+	}
+	_, err := fetcher.FetchChannelStats(ctx, channels[:1])
+	return err
+}
+
+// clearRevokedToken drops the unusable token so polling stops until the user logs in again.
+// The caller must hold tokenMutex.
+func clearRevokedToken(err error) {
+	slog.Error("Refresh token was revoked, re-authentication required — visit /login", "event", "oauth", "error", err)
+	token = nil
+	if err := os.Remove("token.json"); err != nil && !os.IsNotExist(err) {
+		slog.Error("Unable to remove token.json", "error", err)
+	}
+
+	if currentConfig().NotifyAuthFailure {
+		go broadcastMessage(context.Background(), "auth_lost", "YouTube authorization lost", "The YouTube refresh token was revoked, subscriber monitoring is stopped until you log in again via /login.")
+	}
+}
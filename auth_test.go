@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestTokenNeedsRefresh(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		expiry time.Time
+		skew   time.Duration
+		want   bool
+	}{
+		{name: "no expiry", expiry: time.Time{}, skew: time.Minute, want: false},
+		{name: "expired", expiry: now.Add(-time.Second), skew: time.Minute, want: true},
+		{name: "within skew", expiry: now.Add(30 * time.Second), skew: time.Minute, want: true},
+		{name: "exactly at skew", expiry: now.Add(time.Minute), skew: time.Minute, want: false},
+		{name: "beyond skew", expiry: now.Add(time.Minute + time.Second), skew: time.Minute, want: false},
+		{name: "zero skew expires now", expiry: now, skew: 0, want: false},
+		{name: "zero skew expired", expiry: now.Add(-time.Nanosecond), skew: 0, want: true},
+		{name: "long skew", expiry: now.Add(50 * time.Minute), skew: time.Hour, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := &oauth2.Token{AccessToken: "access", Expiry: tt.expiry}
+			if got := tokenNeedsRefresh(tok, now, tt.skew); got != tt.want {
+				t.Errorf("tokenNeedsRefresh(expiry %v, skew %v) = %v, want %v", tt.expiry, tt.skew, got, tt.want)
+			}
+		})
+	}
+}
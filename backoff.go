@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	defaultMaxBackoff = 30 * time.Minute
+	// Quota is restored daily, retrying sooner than this only wastes more of it
+	quotaBackoff = time.Hour
+	// Fraction of the delay randomly added or removed
+	backoffJitter = 0.2
+)
+
+// pollBackoff stretches the poll interval after consecutive YouTube API failures
+type pollBackoff struct {
+	failures int
+	quota    bool
+}
+
+// record updates the state with the outcome of the last poll, a nil error resets it
+func (b *pollBackoff) record(err error) {
+	if err == nil {
+		if b.failures > 0 {
+			slog.Info("YouTube API recovered", "event", "poll", "failures", b.failures)
+		}
+		b.failures = 0
+		b.quota = false
+		return
+	}
+	b.failures++
+	b.quota = isQuotaError(err)
+}
+
+// delay returns how long to wait before the next poll
+func (b *pollBackoff) delay(base time.Duration) time.Duration {
+	if b.failures == 0 {
+		return base
+	}
+
+	ceiling := defaultMaxBackoff
+	if currentConfig().MaxBackoff > 0 {
+		ceiling = time.Duration(currentConfig().MaxBackoff) * time.Second
+	}
+	if ceiling < base {
+		ceiling = base
+	}
+
+	d := base
+	for i := 0; i < b.failures && d < ceiling; i++ {
+		d *= 2
+	}
+	if d > ceiling {
+		d = ceiling
+	}
+	if b.quota && d < quotaBackoff {
+		d = quotaBackoff
+	}
+
+	jitter := time.Duration((rand.Float64()*2 - 1) * backoffJitter * float64(d))
+	return d + jitter
+}
+
+// withPollJitter shifts d by up to poll_jitter either way so instances started together spread
+// their calls. The first poll is only ever delayed.
+func withPollJitter(d time.Duration, first bool) time.Duration {
+	jitter := currentConfig().pollJitter
+	if jitter <= 0 {
+		return d
+	}
+	if first {
+		return d + time.Duration(rand.Int63n(int64(jitter)))
+	}
+	return d + time.Duration(rand.Int63n(2*int64(jitter))) - jitter
+}
+
+// isQuotaError reports whether err means the API quota or rate limit is exhausted
+func isQuotaError(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.Code == http.StatusTooManyRequests {
+		return true
+	}
+	if apiErr.Code != http.StatusForbidden {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		switch item.Reason {
+		case "quotaExceeded", "dailyLimitExceeded", "rateLimitExceeded", "userRateLimitExceeded":
+			return true
+		}
+	}
+	return false
+}
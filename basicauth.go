@@ -0,0 +1,38 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"slices"
+)
+
+// Paths reachable without Basic Auth unless http_auth_exempt is set. The OAuth callback is
+// not among them: the browser that logged in sends the credentials along with the redirect
+// from Google. Add /oauth2callback to http_auth_exempt when it does not, the state parameter
+// still protects the callback.
+var defaultHTTPAuthExempt = []string{"/healthz", "/readyz"}
+
+func basicAuthValid(r *http.Request) bool {
+	cfg := currentConfig()
+	username, password, ok := r.BasicAuth()
+	if cfg.HTTPUsername == "" || !ok {
+		return false
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(cfg.HTTPUsername)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(password), []byte(cfg.HTTPPassword)) == 1
+	return userOK && passOK
+}
+
+// requireBasicAuth protects every path but the exempt ones with http_username and
+// http_password. A valid bearer token is accepted instead. It is a no-op without credentials.
+func requireBasicAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := currentConfig()
+		if cfg.HTTPUsername != "" && !slices.Contains(cfg.HTTPAuthExempt, r.URL.Path) && !basicAuthValid(r) && !bearerTokenValid(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="youtube-notification", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,728 @@
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigPath is used when neither -config nor CONFIG_PATH is given
+const defaultConfigPath = "config.yaml"
+
+var (
+	config      *Config
+	configMutex sync.RWMutex
+)
+
+// currentConfig returns the active configuration, it may be replaced by a SIGHUP reload
+func currentConfig() *Config {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return config
+}
+
+func setConfig(c *Config) {
+	configMutex.Lock()
+	config = c
+	configMutex.Unlock()
+}
+
+// Configuration
+type Config struct {
+	ClientID               string          `yaml:"client_id"`
+	ClientSecret           string          `yaml:"client_secret"`
+	RedirectURL            string          `yaml:"redirect_url"`
+	ServiceAccountFile     string          `yaml:"service_account_file"`
+	OAuthExchangeAttempts  int             `yaml:"oauth_exchange_attempts"`
+	ValidateTokenOnStart   bool            `yaml:"validate_token_on_start"`
+	APIKey                 string          `yaml:"api_key"`
+	WebhookURL             string          `yaml:"webhook_url"`
+	Webhooks               []WebhookConfig `yaml:"webhooks"`
+	WebhookSecret          string          `yaml:"webhook_secret"`
+	WebhookDurable         bool            `yaml:"webhook_durable"`
+	WebhookMaxAge          string          `yaml:"webhook_max_age"`
+	WebhookCAFile          string          `yaml:"webhook_ca_file"`
+	DiscordWebhookURL      string          `yaml:"discord_webhook_url"`
+	MatrixHomeserverURL    string          `yaml:"matrix_homeserver_url"`
+	MatrixAccessToken      string          `yaml:"matrix_access_token"`
+	MatrixRoomID           string          `yaml:"matrix_room_id"`
+	MatrixHTML             bool            `yaml:"matrix_html"`
+	PushoverToken          string          `yaml:"pushover_token"`
+	PushoverUser           string          `yaml:"pushover_user"`
+	PushoverPriority       int             `yaml:"pushover_priority"`
+	PushoverSound          string          `yaml:"pushover_sound"`
+	SlackWebhookURL        string          `yaml:"slack_webhook_url"`
+	ChannelID              string          `yaml:"channel_id"`
+	Channels               []ChannelConfig `yaml:"channels"`
+	SMTPHost               string          `yaml:"smtp_host"`
+	SMTPPort               int             `yaml:"smtp_port"`
+	SMTPUsername           string          `yaml:"smtp_username"`
+	SMTPPassword           string          `yaml:"smtp_password"`
+	EmailFrom              string          `yaml:"email_from"`
+	EmailTo                []string        `yaml:"email_to"`
+	BotKey                 string          `yaml:"bot_key"`
+	DryRun                 bool            `yaml:"dry_run"`
+	ChatIDs                []ChatConfig    `yaml:"chat_ids"`
+	TelegramBots           []BotConfig     `yaml:"telegram_bots"`
+	TelegramRateLimit      float64         `yaml:"telegram_rate_limit"`
+	MaxMessageLength       map[string]int  `yaml:"max_message_length"`
+	SleepTime              int             `yaml:"sleep_time"`
+	HTTPTimeout            string          `yaml:"http_timeout"`
+	ProxyURL               string          `yaml:"proxy_url"`
+	UserAgent              string          `yaml:"user_agent"`
+	APITimeout             string          `yaml:"api_timeout"`
+	APIBaseURL             string          `yaml:"api_base_url"`
+	TokenRefreshSkew       string          `yaml:"token_refresh_skew"`
+	PollInterval           string          `yaml:"poll_interval"`
+	PollJitter             string          `yaml:"poll_jitter"`
+	PollConcurrency        int             `yaml:"poll_concurrency"`
+	MinPollInterval        string          `yaml:"min_poll_interval"`
+	ListenAddr             string          `yaml:"listen_addr"`
+	TLSCertFile            string          `yaml:"tls_cert_file"`
+	TLSKeyFile             string          `yaml:"tls_key_file"`
+	TLSRedirectAddr        string          `yaml:"tls_redirect_addr"`
+	Milestones             []uint64        `yaml:"milestones"`
+	Target                 uint64          `yaml:"target"`
+	TargetNotifyBelow      bool            `yaml:"target_notify_below"`
+	AlertChatIDs           []ChatConfig    `yaml:"alert_chat_ids"`
+	AlertWebhookURL        string          `yaml:"alert_webhook_url"`
+	DropThreshold          uint64          `yaml:"drop_alert_threshold"`
+	MaxBackoff             int             `yaml:"max_backoff"`
+	DailyQuota             int64           `yaml:"daily_quota"`
+	DBPath                 string          `yaml:"db_path"`
+	LogLevel               string          `yaml:"log_level"`
+	LogFormat              string          `yaml:"log_format"`
+	LogFile                string          `yaml:"log_file"`
+	LogMaxSizeMB           int             `yaml:"log_max_size_mb"`
+	LogStderr              bool            `yaml:"log_stderr"`
+	MessageTemplate        string          `yaml:"message_template"`
+	WebhookPayloadTemplate string          `yaml:"webhook_payload_template"`
+	CompactCounts          bool            `yaml:"compact_counts"`
+	MinChange              uint64          `yaml:"min_change"`
+	MinChangePercent       float64         `yaml:"min_change_percent"`
+	MinChangeMode          string          `yaml:"min_change_mode"`
+	NotifyCooldown         string          `yaml:"notify_cooldown"`
+	Cooldown               Cooldowns       `yaml:"cooldown"`
+	DigestInterval         string          `yaml:"digest_interval"`
+	DeltaWindows           []string        `yaml:"delta_windows"`
+	MetricsEnabled         bool            `yaml:"metrics_enabled"`
+	MetricsPath            string          `yaml:"metrics_path"`
+	YouTubeParts           []string        `yaml:"youtube_parts"`
+	Language               string          `yaml:"language"`
+	WatchMetrics           []string        `yaml:"watch_metrics"`
+	WatchLivestreams       bool            `yaml:"watch_livestreams"`
+	NotifyUploads          bool            `yaml:"notify_uploads"`
+	NotifyLivestreamEnd    bool            `yaml:"notify_livestream_end"`
+	BearerToken            string          `yaml:"bearer_token"`
+	HTTPUsername           string          `yaml:"http_username"`
+	HTTPPassword           string          `yaml:"http_password"`
+	HTTPAuthExempt         []string        `yaml:"http_auth_exempt"`
+	NotificationLogSize    int             `yaml:"notification_log_size"`
+	NotifyAuthFailure      bool            `yaml:"notify_auth_failure"`
+	NotifyOnStart          bool            `yaml:"notify_on_start"`
+	Aggregate              bool            `yaml:"aggregate"`
+	AggregateOnly          bool            `yaml:"aggregate_only"`
+	AggregateLabel         string          `yaml:"aggregate_label"`
+
+	// Derived from the fields above by loadConfig
+	logger          *slog.Logger
+	messageTemplate *template.Template
+	// nil for the default webhook payload
+	webhookPayloadTemplate *template.Template
+	pollInterval           time.Duration
+	minPollInterval        time.Duration
+	notifyCooldown         time.Duration
+	platformCooldowns      map[string]time.Duration
+	pollJitter             time.Duration
+	digestInterval         time.Duration
+	deltaWindows           []deltaWindow
+	webhookMaxAge          time.Duration
+	httpTimeout            time.Duration
+	apiTimeout             time.Duration
+	tokenRefreshSkew       time.Duration
+	proxyURL               *url.URL
+	apiBaseURL             string
+	webhookRootCAs         *x509.CertPool
+}
+
+// ChannelConfig is a single monitored YouTube channel
+type ChannelConfig struct {
+	ChannelID string `yaml:"channel_id"`
+	// Handle such as @name, used instead of channel_id and resolved to it at startup
+	Handle string `yaml:"channel_handle"`
+	Label  string `yaml:"label"`
+	// Target overrides the global subscriber target for this channel
+	Target uint64 `yaml:"target"`
+	// Routing overrides of the global settings, see routing.go
+	ChatIDs         []ChatConfig `yaml:"chat_ids"`
+	WebhookURL      string       `yaml:"webhook_url"`
+	SlackWebhookURL string       `yaml:"slack_webhook_url"`
+}
+
+// Name returns the label of the channel, falling back to its YouTube title and then its ID
+func (c ChannelConfig) Name() string {
+	if c.Label != "" {
+		return c.Label
+	}
+	if title := channelTitle(c.ChannelID); title != "" {
+		return title
+	}
+	if c.ChannelID == "" {
+		return c.Handle
+	}
+	return c.ChannelID
+}
+
+const (
+	defaultPollInterval = time.Minute
+	// Polling faster than this burns through the daily API quota, min_poll_interval overrides it
+	defaultMinPollInterval = 5 * time.Second
+)
+
+// languagePattern matches the BCP-47 style codes accepted by the hl parameter
+var languagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// validYouTubeParts are the parts accepted by Channels.List
+var validYouTubeParts = map[string]bool{
+	"auditDetails":        true,
+	"brandingSettings":    true,
+	"contentDetails":      true,
+	"contentOwnerDetails": true,
+	"id":                  true,
+	"localizations":       true,
+	"snippet":             true,
+	"statistics":          true,
+	"status":              true,
+	"topicDetails":        true,
+}
+
+// WebhookConfig is a single generic webhook receiver
+type WebhookConfig struct {
+	URL         string            `yaml:"url"`
+	Headers     map[string]string `yaml:"headers"`
+	ContentType string            `yaml:"content_type"`
+}
+
+// loadConfig reads and validates the configuration file at path
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file error: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := decodeConfig(path, data, cfg); err != nil {
+		return nil, fmt.Errorf("decode config file error: %w", err)
+	}
+
+	applyEnvOverrides(cfg)
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// Environment variables overriding settings of the config file, mostly secrets that should
+// not be written into a mounted file
+const (
+	envTelegramChatIDs = "TELEGRAM_CHAT_IDS" // comma-separated
+	envTelegramBotKey  = "TELEGRAM_BOT_KEY"
+	envWebhookURL      = "WEBHOOK_URL"
+)
+
+// applyEnvOverrides replaces settings with the environment variables that are set. The
+// precedence is environment, then config file, then the defaults filled in by validate.
+func applyEnvOverrides(c *Config) {
+	if v := os.Getenv(envTelegramChatIDs); v != "" {
+		c.ChatIDs = nil
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				c.ChatIDs = append(c.ChatIDs, ChatConfig{ChatID: id})
+			}
+		}
+	}
+	if v := os.Getenv(envTelegramBotKey); v != "" {
+		c.BotKey = v
+	}
+	if v := os.Getenv(envWebhookURL); v != "" {
+		c.WebhookURL = v
+	}
+}
+
+// checkRedirectURL makes sure Google sends the browser back to handleOAuth2Callback. A path
+// prefix is only warned about, a reverse proxy may strip it.
+func (c *Config) checkRedirectURL() error {
+	u, err := url.Parse(c.RedirectURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("redirect_url must be a valid http(s) URL, got %q", c.RedirectURL)
+	}
+	if !strings.HasSuffix(u.Path, oauthCallbackPath) {
+		return fmt.Errorf("redirect_url must end in %s to reach the OAuth callback, got %q", oauthCallbackPath, c.RedirectURL)
+	}
+	if u.Path != oauthCallbackPath {
+		c.logger.Warn("redirect_url has a path prefix, the OAuth callback is only served at "+oauthCallbackPath+" unless a reverse proxy strips it", "redirect_url", c.RedirectURL)
+	}
+	return nil
+}
+
+// decodeConfig decodes data in the format given by the extension of path, YAML unless it is
+// .json or .toml. Every format goes through the YAML decoder so the yaml field tags and
+// custom unmarshalers apply to all of them.
+func decodeConfig(path string, data []byte, cfg *Config) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		// JSON is valid YAML, decoding it first only reports syntax errors in JSON terms
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+	case ".toml":
+		var v map[string]any
+		if _, err := toml.Decode(string(data), &v); err != nil {
+			return err
+		}
+		var err error
+		data, err = yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+	}
+	return yaml.NewDecoder(bytes.NewReader(data)).Decode(cfg)
+}
+
+// validate checks the configuration and fills in defaults and derived values
+func (c *Config) validate() error {
+	var err error
+	if c.LogMaxSizeMB < 0 {
+		return fmt.Errorf("log_max_size_mb must be positive, got %d", c.LogMaxSizeMB)
+	}
+	c.logger, err = newLogger(c.LogLevel, c.LogFormat, c.LogFile, int64(c.LogMaxSizeMB)<<20, c.LogStderr)
+	if err != nil {
+		return err
+	}
+
+	// A single top-level channel_id is kept working as a one-element list
+	if c.ChannelID != "" {
+		c.Channels = append([]ChannelConfig{{ChannelID: c.ChannelID}}, c.Channels...)
+	}
+
+	// Likewise a single webhook_url becomes the first entry of webhooks
+	if c.WebhookURL != "" {
+		c.Webhooks = append([]WebhookConfig{{URL: c.WebhookURL}}, c.Webhooks...)
+	}
+
+	if len(c.Webhooks) == 0 || len(c.Channels) == 0 {
+		return errors.New("a webhook and at least one channel are required")
+	}
+	// A service account or an API key replaces the OAuth login
+	if c.ServiceAccountFile == "" && c.APIKey == "" && (c.ClientID == "" || c.ClientSecret == "" || c.RedirectURL == "") {
+		return errors.New("client_id, client_secret and redirect_url are required unless service_account_file or api_key is set")
+	}
+	if c.RedirectURL != "" && c.ServiceAccountFile == "" && c.APIKey == "" {
+		if err := c.checkRedirectURL(); err != nil {
+			return err
+		}
+	}
+
+	if c.OAuthExchangeAttempts < 0 {
+		return fmt.Errorf("oauth_exchange_attempts must be positive, got %d", c.OAuthExchangeAttempts)
+	}
+	if c.ServiceAccountFile != "" && c.APIKey != "" {
+		return errors.New("service_account_file and api_key are mutually exclusive")
+	}
+
+	for _, webhook := range c.Webhooks {
+		u, err := url.Parse(webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook url must be a valid http(s) URL, got %q", webhook.URL)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i := range c.Channels {
+		channel := &c.Channels[i]
+		if channel.ChannelID == "" && channel.Handle == "" {
+			return errors.New("channel without channel_id or channel_handle")
+		}
+		if channel.ChannelID != "" && channel.Handle != "" {
+			return fmt.Errorf("channel %s sets both channel_id and channel_handle", channel.ChannelID)
+		}
+		// Handles resolved by an earlier run are known without asking YouTube again
+		if channel.Handle != "" {
+			channel.ChannelID = resolvedChannelID(channel.Handle)
+		}
+		key := channel.ChannelID
+		if key == "" {
+			key = channel.Handle
+		}
+		if seen[key] {
+			return fmt.Errorf("duplicate channel %s", key)
+		}
+		seen[key] = true
+
+		if channel.WebhookURL != "" {
+			u, err := url.Parse(channel.WebhookURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("webhook_url of channel %s must be a valid http(s) URL, got %q", key, channel.WebhookURL)
+			}
+		}
+		if channel.SlackWebhookURL != "" {
+			u, err := url.Parse(channel.SlackWebhookURL)
+			if err != nil || u.Scheme != "https" || u.Host == "" {
+				return fmt.Errorf("slack_webhook_url of channel %s must be a valid https URL, got %q", key, channel.SlackWebhookURL)
+			}
+		}
+		for _, chat := range channel.ChatIDs {
+			if chat.ChatID == "" {
+				return fmt.Errorf("telegram chat without chat_id in channel %s", key)
+			}
+		}
+	}
+
+	if c.AlertWebhookURL != "" {
+		u, err := url.Parse(c.AlertWebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("alert_webhook_url must be a valid http(s) URL, got %q", c.AlertWebhookURL)
+		}
+	}
+
+	if c.DiscordWebhookURL != "" {
+		u, err := url.Parse(c.DiscordWebhookURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("discord_webhook_url must be a valid https URL, got %q", c.DiscordWebhookURL)
+		}
+	}
+
+	if c.SlackWebhookURL != "" {
+		u, err := url.Parse(c.SlackWebhookURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("slack_webhook_url must be a valid https URL, got %q", c.SlackWebhookURL)
+		}
+	}
+
+	if c.MatrixHomeserverURL != "" {
+		u, err := url.Parse(c.MatrixHomeserverURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("matrix_homeserver_url must be a valid http(s) URL, got %q", c.MatrixHomeserverURL)
+		}
+		if c.MatrixAccessToken == "" || c.MatrixRoomID == "" {
+			return errors.New("matrix_access_token and matrix_room_id are required when matrix_homeserver_url is set")
+		}
+	}
+
+	if (c.PushoverToken == "") != (c.PushoverUser == "") {
+		return errors.New("pushover_token and pushover_user must be set together")
+	}
+	if c.PushoverPriority < -2 || c.PushoverPriority > 2 {
+		return fmt.Errorf("pushover_priority must be between -2 and 2, got %d", c.PushoverPriority)
+	}
+
+	if c.SMTPHost != "" && (c.EmailFrom == "" || len(c.EmailTo) == 0) {
+		return errors.New("email_from and email_to are required when smtp_host is set")
+	}
+
+	// bot_key and chat_ids are kept working as the first entry of telegram_bots
+	if c.BotKey != "" || len(c.ChatIDs) > 0 {
+		c.TelegramBots = append([]BotConfig{{BotKey: c.BotKey, ChatIDs: c.ChatIDs}}, c.TelegramBots...)
+	}
+	// chat_ids then holds the chats of every bot, each knowing the bot that sends to it
+	c.ChatIDs = nil
+	for _, bot := range c.TelegramBots {
+		if bot.BotKey == "" && len(bot.ChatIDs) > 0 {
+			return errors.New("telegram chat_ids need a bot_key")
+		}
+		for _, chat := range bot.ChatIDs {
+			chat.botKey = bot.BotKey
+			c.ChatIDs = append(c.ChatIDs, chat)
+		}
+	}
+	// Drop alerts and the chat_ids of a channel are sent by the first bot
+	var defaultBotKey string
+	if len(c.TelegramBots) > 0 {
+		defaultBotKey = c.TelegramBots[0].BotKey
+	}
+	for i := range c.AlertChatIDs {
+		c.AlertChatIDs[i].botKey = defaultBotKey
+	}
+	for i := range c.Channels {
+		for j := range c.Channels[i].ChatIDs {
+			c.Channels[i].ChatIDs[j].botKey = defaultBotKey
+		}
+		if defaultBotKey == "" && len(c.Channels[i].ChatIDs) > 0 {
+			return fmt.Errorf("chat_ids of channel %s need a telegram bot_key", c.Channels[i].Name())
+		}
+	}
+	if defaultBotKey == "" && len(c.AlertChatIDs) > 0 {
+		return errors.New("alert_chat_ids need a telegram bot_key")
+	}
+
+	for _, chat := range append(slices.Clip(c.ChatIDs), c.AlertChatIDs...) {
+		if chat.ChatID == "" {
+			return errors.New("telegram chat without chat_id")
+		}
+	}
+
+	if c.TelegramRateLimit < 0 {
+		return fmt.Errorf("telegram_rate_limit must be positive, got %v", c.TelegramRateLimit)
+	}
+	if c.TelegramRateLimit == 0 {
+		c.TelegramRateLimit = defaultTelegramRateLimit
+	}
+	if err := validateMessageLimits(c.MaxMessageLength); err != nil {
+		return err
+	}
+
+	c.messageTemplate, err = parseMessageTemplate(c.MessageTemplate)
+	if err != nil {
+		return fmt.Errorf("message_template: %w", err)
+	}
+	c.webhookPayloadTemplate, err = parseWebhookPayloadTemplate(c.WebhookPayloadTemplate, c.messageTemplate)
+	if err != nil {
+		return fmt.Errorf("webhook_payload_template: %w", err)
+	}
+
+	// poll_interval takes precedence over the older sleep_time in seconds
+	switch {
+	case c.PollInterval != "":
+		c.pollInterval, err = time.ParseDuration(c.PollInterval)
+		if err != nil {
+			return fmt.Errorf("poll_interval: %w", err)
+		}
+	case c.SleepTime != 0:
+		c.pollInterval = time.Duration(c.SleepTime) * time.Second
+	default:
+		c.pollInterval = defaultPollInterval
+	}
+	if c.pollInterval <= 0 {
+		return fmt.Errorf("poll interval must be positive, got %v", c.pollInterval)
+	}
+	c.minPollInterval = defaultMinPollInterval
+	if c.MinPollInterval != "" {
+		c.minPollInterval, err = time.ParseDuration(c.MinPollInterval)
+		if err != nil {
+			return fmt.Errorf("min_poll_interval: %w", err)
+		}
+		if c.minPollInterval <= 0 {
+			return fmt.Errorf("min_poll_interval must be positive, got %v", c.minPollInterval)
+		}
+	}
+	if c.pollInterval < c.minPollInterval {
+		c.logger.Warn("Poll interval is below the minimum and was raised to protect the API quota",
+			"poll_interval", c.pollInterval.String(), "min_poll_interval", c.minPollInterval.String())
+		c.pollInterval = c.minPollInterval
+	}
+
+	if c.MinChangePercent < 0 {
+		return fmt.Errorf("min_change_percent must be positive, got %v", c.MinChangePercent)
+	}
+	switch c.MinChangeMode {
+	case "":
+		c.MinChangeMode = minChangeAny
+	case minChangeAny, minChangeAll:
+	default:
+		return fmt.Errorf("invalid min_change_mode %q, expected any or all", c.MinChangeMode)
+	}
+
+	if c.PollJitter != "" {
+		c.pollJitter, err = time.ParseDuration(c.PollJitter)
+		if err != nil {
+			return fmt.Errorf("poll_jitter: %w", err)
+		}
+		// Keeps polls close to the configured interval
+		if c.pollJitter < 0 || c.pollJitter > c.pollInterval/2 {
+			return fmt.Errorf("poll_jitter must be between 0 and half the poll interval (%v), got %v", c.pollInterval/2, c.pollJitter)
+		}
+	}
+
+	if c.NotifyCooldown != "" {
+		c.notifyCooldown, err = time.ParseDuration(c.NotifyCooldown)
+		if err != nil {
+			return fmt.Errorf("notify_cooldown: %w", err)
+		}
+	}
+	c.platformCooldowns, err = parseCooldowns(c.Cooldown)
+	if err != nil {
+		return fmt.Errorf("cooldown: %w", err)
+	}
+
+	if c.DigestInterval != "" {
+		c.digestInterval, err = time.ParseDuration(c.DigestInterval)
+		if err != nil {
+			return fmt.Errorf("digest_interval: %w", err)
+		}
+		if c.digestInterval < time.Minute {
+			return fmt.Errorf("digest_interval must be at least 1m, got %v", c.digestInterval)
+		}
+	}
+
+	c.deltaWindows, err = parseDeltaWindows(c.DeltaWindows)
+	if err != nil {
+		return fmt.Errorf("delta_windows: %w", err)
+	}
+	if len(c.deltaWindows) > 0 && c.DBPath == "" {
+		return errors.New("delta_windows are computed from the history and need db_path")
+	}
+
+	c.webhookMaxAge = defaultWebhookMaxAge
+	if c.WebhookMaxAge != "" {
+		c.webhookMaxAge, err = time.ParseDuration(c.WebhookMaxAge)
+		if err != nil {
+			return fmt.Errorf("webhook_max_age: %w", err)
+		}
+		if c.webhookMaxAge <= 0 {
+			return fmt.Errorf("webhook_max_age must be positive, got %v", c.webhookMaxAge)
+		}
+	}
+
+	c.httpTimeout = defaultHTTPTimeout
+	if c.HTTPTimeout != "" {
+		c.httpTimeout, err = time.ParseDuration(c.HTTPTimeout)
+		if err != nil {
+			return fmt.Errorf("http_timeout: %w", err)
+		}
+		if c.httpTimeout <= 0 {
+			return fmt.Errorf("http_timeout must be positive, got %v", c.httpTimeout)
+		}
+	}
+
+	if c.AggregateOnly && !c.Aggregate {
+		return errors.New("aggregate_only needs aggregate to be enabled")
+	}
+
+	if c.PollConcurrency < 0 {
+		return fmt.Errorf("poll_concurrency must be positive, got %d", c.PollConcurrency)
+	}
+	// Serial polling unless asked otherwise
+	if c.PollConcurrency == 0 {
+		c.PollConcurrency = 1
+	}
+
+	if c.NotificationLogSize < 0 {
+		return fmt.Errorf("notification_log_size must be positive, got %d", c.NotificationLogSize)
+	}
+	if c.NotificationLogSize == 0 {
+		c.NotificationLogSize = defaultNotificationLogSize
+	}
+
+	if c.UserAgent == "" {
+		c.UserAgent = defaultUserAgent
+	}
+
+	if c.ProxyURL != "" {
+		c.proxyURL, err = url.Parse(c.ProxyURL)
+		if err != nil || (c.proxyURL.Scheme != "http" && c.proxyURL.Scheme != "https" && c.proxyURL.Scheme != "socks5") || c.proxyURL.Host == "" {
+			return fmt.Errorf("proxy_url must be an http, https or socks5 URL, got %q", c.ProxyURL)
+		}
+	}
+
+	if c.APIBaseURL != "" {
+		u, err := url.Parse(c.APIBaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("api_base_url must be an http or https URL, got %q", c.APIBaseURL)
+		}
+		// API paths such as youtube/v3/channels are appended to the base URL
+		c.apiBaseURL = strings.TrimSuffix(u.String(), "/") + "/"
+	}
+
+	if c.WebhookCAFile != "" {
+		c.webhookRootCAs, err = loadCAFile(c.WebhookCAFile)
+		if err != nil {
+			return fmt.Errorf("webhook_ca_file: %w", err)
+		}
+	}
+
+	c.tokenRefreshSkew = defaultTokenRefreshSkew
+	if c.TokenRefreshSkew != "" {
+		c.tokenRefreshSkew, err = time.ParseDuration(c.TokenRefreshSkew)
+		if err != nil {
+			return fmt.Errorf("token_refresh_skew: %w", err)
+		}
+		if c.tokenRefreshSkew < 0 {
+			return fmt.Errorf("token_refresh_skew must not be negative, got %v", c.tokenRefreshSkew)
+		}
+	}
+
+	if c.APITimeout != "" {
+		c.apiTimeout, err = time.ParseDuration(c.APITimeout)
+		if err != nil {
+			return fmt.Errorf("api_timeout: %w", err)
+		}
+		if c.apiTimeout <= 0 {
+			return fmt.Errorf("api_timeout must be positive, got %v", c.apiTimeout)
+		}
+	}
+
+	if c.Language != "" && !languagePattern.MatchString(c.Language) {
+		return fmt.Errorf("language must be a language code such as de or pt-BR, got %q", c.Language)
+	}
+
+	if len(c.YouTubeParts) == 0 {
+		c.YouTubeParts = []string{"statistics"}
+	}
+	hasStatistics := false
+	for _, part := range c.YouTubeParts {
+		if !validYouTubeParts[part] {
+			return fmt.Errorf("unknown youtube_parts entry %q", part)
+		}
+		hasStatistics = hasStatistics || part == "statistics"
+	}
+	if !hasStatistics {
+		return errors.New("youtube_parts must include statistics")
+	}
+
+	if len(c.WatchMetrics) == 0 {
+		c.WatchMetrics = []string{metricSubscribers}
+	}
+	for _, metric := range c.WatchMetrics {
+		if _, ok := metricTitles[metric]; !ok {
+			return fmt.Errorf("unknown watch_metrics entry %q", metric)
+		}
+	}
+
+	if c.MetricsPath != "" && !strings.HasPrefix(c.MetricsPath, "/") {
+		return fmt.Errorf("metrics_path must start with /, got %q", c.MetricsPath)
+	}
+
+	if (c.HTTPUsername == "") != (c.HTTPPassword == "") {
+		return errors.New("http_username and http_password must be set together")
+	}
+	if c.HTTPAuthExempt == nil {
+		c.HTTPAuthExempt = defaultHTTPAuthExempt
+	}
+
+	if c.ListenAddr == "" {
+		c.ListenAddr = ":8080"
+	}
+	if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		return fmt.Errorf("listen_addr %q: %w", c.ListenAddr, err)
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("tls_cert_file and tls_key_file must be set together")
+	}
+	if c.TLSRedirectAddr != "" {
+		if !c.tlsEnabled() {
+			return errors.New("tls_redirect_addr requires tls_cert_file and tls_key_file")
+		}
+		if _, _, err := net.SplitHostPort(c.TLSRedirectAddr); err != nil {
+			return fmt.Errorf("tls_redirect_addr %q: %w", c.TLSRedirectAddr, err)
+		}
+	}
+	return nil
+}
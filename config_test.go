@@ -0,0 +1,142 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The same configuration in every supported format
+var configFormats = map[string]string{
+	"config.yaml": `
+api_key: secret
+sleep_time: 90
+dry_run: true
+telegram_rate_limit: 0.5
+milestones: [1000, 5000]
+max_message_length:
+  telegram: 2000
+cooldown:
+  telegram: 1h
+channels:
+  - channel_id: UCaaaaaaaaaaaaaaaaaaaaaa
+    label: First
+    chat_ids:
+      - "-100111"
+      - chat_id: "-100222"
+        message_thread_id: 7
+webhooks:
+  - url: https://example.com/hook
+    headers:
+      X-Token: abc
+`,
+	"config.json": `{
+  "api_key": "secret",
+  "sleep_time": 90,
+  "dry_run": true,
+  "telegram_rate_limit": 0.5,
+  "milestones": [1000, 5000],
+  "max_message_length": {"telegram": 2000},
+  "cooldown": {"telegram": "1h"},
+  "channels": [{
+    "channel_id": "UCaaaaaaaaaaaaaaaaaaaaaa",
+    "label": "First",
+    "chat_ids": ["-100111", {"chat_id": "-100222", "message_thread_id": 7}]
+  }],
+  "webhooks": [{"url": "https://example.com/hook", "headers": {"X-Token": "abc"}}]
+}`,
+	"config.toml": `
+api_key = "secret"
+sleep_time = 90
+dry_run = true
+telegram_rate_limit = 0.5
+milestones = [1000, 5000]
+
+[max_message_length]
+telegram = 2000
+
+[cooldown]
+telegram = "1h"
+
+[[channels]]
+channel_id = "UCaaaaaaaaaaaaaaaaaaaaaa"
+label = "First"
+chat_ids = ["-100111", { chat_id = "-100222", message_thread_id = 7 }]
+
+[[webhooks]]
+url = "https://example.com/hook"
+headers = { X-Token = "abc" }
+`,
+}
+
+func TestDecodeConfigFormats(t *testing.T) {
+	want := &Config{
+		APIKey:            "secret",
+		SleepTime:         90,
+		DryRun:            true,
+		TelegramRateLimit: 0.5,
+		Milestones:        []uint64{1000, 5000},
+		MaxMessageLength:  map[string]int{"telegram": 2000},
+		Cooldown:          Cooldowns{"telegram": "1h"},
+		Channels: []ChannelConfig{{
+			ChannelID: "UCaaaaaaaaaaaaaaaaaaaaaa",
+			Label:     "First",
+			ChatIDs:   []ChatConfig{{ChatID: "-100111"}, {ChatID: "-100222", MessageThreadID: 7}},
+		}},
+		Webhooks: []WebhookConfig{{URL: "https://example.com/hook", Headers: map[string]string{"X-Token": "abc"}}},
+	}
+	for path, data := range configFormats {
+		t.Run(path, func(t *testing.T) {
+			cfg := &Config{}
+			if err := decodeConfig(path, []byte(data), cfg); err != nil {
+				t.Fatalf("decodeConfig: %v", err)
+			}
+			if !reflect.DeepEqual(cfg, want) {
+				t.Errorf("decoded\n%+v\nwant\n%+v", cfg, want)
+			}
+		})
+	}
+}
+
+func TestDecodeConfigSyntaxErrors(t *testing.T) {
+	for _, path := range []string{"config.yaml", "config.json", "config.toml"} {
+		if err := decodeConfig(path, []byte("api_key: [\n"), &Config{}); err == nil {
+			t.Errorf("decodeConfig(%s) accepted a syntax error", path)
+		}
+	}
+}
+
+func TestValidateAlertWebhookURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://example.com/alerts", true},
+		{"http://localhost:9000/alerts", true},
+		{"htps://example.com/alerts", false},
+		{"example.com/alerts", false},
+		{"https://", false},
+	}
+	for _, tt := range tests {
+		cfg := &Config{}
+		if err := decodeConfig("config.yaml", []byte(testConfigYAML+"alert_webhook_url: \""+tt.url+"\"\n"), cfg); err != nil {
+			t.Fatal(err)
+		}
+		if err := cfg.validate(); (err == nil) != tt.valid {
+			t.Errorf("validate with alert_webhook_url %q returned %v, want valid %v", tt.url, err, tt.valid)
+		}
+	}
+}
+
+func TestValidateNamesHandleOnlyChannels(t *testing.T) {
+	resetChannelHandles(t)
+	cfg := &Config{}
+	yaml := testConfigYAML + "channels:\n  - channel_handle: \"@someone\"\n    webhook_url: not-a-url\n"
+	if err := decodeConfig("config.yaml", []byte(yaml), cfg); err != nil {
+		t.Fatal(err)
+	}
+	err := cfg.validate()
+	if err == nil || !strings.Contains(err.Error(), "channel @someone ") {
+		t.Errorf("validate returned %v, want the error to name channel @someone", err)
+	}
+}
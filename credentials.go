@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi/transport"
+	"google.golang.org/api/youtube/v3"
+)
+
+// serviceAccount is set when service_account_file is configured and replaces the /login flow.
+// Public channel statistics are readable with any service account, private data such as
+// uploads of unlisted videos needs an account that was granted access to the channel.
+var serviceAccount oauth2.TokenSource
+
+// loadServiceAccount reads the JSON key of a Google service account
+func loadServiceAccount(path string) (oauth2.TokenSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := google.CredentialsFromJSON(oauthContext(context.Background()), data, youtube.YoutubeReadonlyScope)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return oauth2.ReuseTokenSource(nil, creds.TokenSource), nil
+}
+
+// serviceAccountClient returns a client authorized by the service account that shares the
+// transport and timeout of httpClient
+func serviceAccountClient(ctx context.Context) *http.Client {
+	client := oauth2.NewClient(oauthContext(ctx), serviceAccount)
+	client.Timeout = httpClient.Timeout
+	return client
+}
+
+// apiKeyClient returns a client that authenticates with api_key. It is enough to read public
+// channel statistics and uploads without any OAuth login.
+func apiKeyClient() *http.Client {
+	return &http.Client{
+		Transport: &transport.APIKey{Key: currentConfig().APIKey, Transport: httpClient.Transport},
+		Timeout:   httpClient.Timeout,
+	}
+}
+
+// usesOAuthLogin reports whether credentials come from the /login flow
+func usesOAuthLogin() bool {
+	return serviceAccount == nil && currentConfig().APIKey == ""
+}
+
+// haveCredentials reports whether YouTube can be queried. The caller must hold tokenMutex.
+func haveCredentials() bool {
+	return token != nil || !usesOAuthLogin()
+}
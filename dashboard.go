@@ -0,0 +1,128 @@
+package main
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	// How far back the dashboard chart reaches
+	dashboardWindow = 30 * 24 * time.Hour
+	// Number of recent subscriber changes listed below each chart
+	dashboardDeltas = 10
+)
+
+type dashboardDelta struct {
+	Time  string `json:"time"`
+	Count string `json:"count"`
+	Delta string `json:"delta"`
+}
+
+type dashboardChannel struct {
+	Name    string           `json:"name"`
+	Current string           `json:"current"`
+	Points  [][2]int64       `json:"points"` // unix seconds, subscriber count
+	Deltas  []dashboardDelta `json:"-"`
+}
+
+// dashboardTemplate draws one SVG line chart per channel from the JSON rendered into the page
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Subscriber dashboard</title>
+<style>
+body { font-family: sans-serif; max-width: 900px; margin: 2em auto; color: #222; }
+svg { width: 100%; height: 240px; background: #fafafa; border: 1px solid #ddd; }
+polyline { fill: none; stroke: #ff0000; stroke-width: 2; }
+td { padding: 0 1em 0 0; }
+.axis { font-size: 12px; fill: #666; }
+</style>
+</head>
+<body>
+<h1>Subscriber dashboard</h1>
+{{if not .HistoryEnabled}}<p>History is disabled, set db_path to record subscriber counts over time.</p>{{end}}
+{{range $i, $c := .Channels}}
+<section>
+<h2>{{$c.Name}}</h2>
+<p>Current subscribers: <strong>{{$c.Current}}</strong></p>
+<svg id="chart-{{$i}}" viewBox="0 0 900 240" preserveAspectRatio="none"></svg>
+{{if $c.Deltas}}<h3>Recent changes</h3>
+<table>{{range $c.Deltas}}<tr><td>{{.Time}}</td><td>{{.Count}}</td><td>{{.Delta}}</td></tr>{{end}}</table>{{end}}
+</section>
+{{end}}
+<script>
+const channels = {{.Channels}};
+channels.forEach((channel, i) => {
+  const svg = document.getElementById("chart-" + i);
+  const points = channel.points || [];
+  if (points.length < 2) {
+    svg.innerHTML = '<text class="axis" x="10" y="20">Not enough history yet</text>';
+    return;
+  }
+  const xs = points.map(p => p[0]), ys = points.map(p => p[1]);
+  const minX = Math.min(...xs), maxX = Math.max(...xs);
+  let minY = Math.min(...ys), maxY = Math.max(...ys);
+  if (minY === maxY) { minY -= 1; maxY += 1; }
+  const line = points.map(p =>
+    ((p[0] - minX) / (maxX - minX || 1) * 880 + 10).toFixed(1) + "," +
+    (230 - (p[1] - minY) / (maxY - minY) * 210).toFixed(1)).join(" ");
+  svg.innerHTML = '<polyline points="' + line + '"/>' +
+    '<text class="axis" x="10" y="15">' + maxY.toLocaleString() + '</text>' +
+    '<text class="axis" x="10" y="235">' + minY.toLocaleString() + '</text>';
+});
+</script>
+</body>
+</html>
+`))
+
+// handleDashboard renders the subscriber history of every configured channel
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	since := time.Now().Add(-dashboardWindow)
+
+	latestCountMutex.Lock()
+	current := make(map[string]int64, len(latestCount))
+	for id, count := range latestCount {
+		current[id] = count
+	}
+	latestCountMutex.Unlock()
+
+	var channels []dashboardChannel
+	for _, channel := range currentConfig().Channels {
+		rows, err := GetHistory(channel.ChannelID, since)
+		if err != nil {
+			slog.Error("Error reading history", "channel_id", channel.ChannelID, "error", err)
+			http.Error(w, "Failed to read history", http.StatusInternalServerError)
+			return
+		}
+
+		c := dashboardChannel{Name: channel.Name(), Current: "unknown", Points: make([][2]int64, 0, len(rows))}
+		if count, ok := current[channel.ChannelID]; ok {
+			c.Current = formatCount(uint64(count))
+		}
+		for i, row := range rows {
+			c.Points = append(c.Points, [2]int64{row.Timestamp.Unix(), int64(row.SubscriberCount)})
+			if i > 0 && row.SubscriberCount != rows[i-1].SubscriberCount {
+				c.Deltas = append([]dashboardDelta{{
+					Time:  row.Timestamp.Format(time.DateTime),
+					Count: formatCount(row.SubscriberCount),
+					Delta: formatDelta(int64(row.SubscriberCount) - int64(rows[i-1].SubscriberCount)),
+				}}, c.Deltas...)
+			}
+		}
+		if len(c.Deltas) > dashboardDeltas {
+			c.Deltas = c.Deltas[:dashboardDeltas]
+		}
+		channels = append(channels, c)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := dashboardTemplate.Execute(w, struct {
+		HistoryEnabled bool
+		Channels       []dashboardChannel
+	}{historyDB != nil, channels})
+	if err != nil {
+		slog.Error("Error rendering dashboard", "error", err)
+	}
+}
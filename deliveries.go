@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Number of deliveries kept for /notifications unless notification_log_size is set
+const defaultNotificationLogSize = 50
+
+// delivery is one message sent, or attempted, to a single platform target
+type delivery struct {
+	Time     time.Time `json:"time"`
+	Platform string    `json:"platform"`
+	Target   string    `json:"target"`
+	Payload  string    `json:"payload"`
+	Status   string    `json:"status"` // sent, failed or dry_run
+	Error    string    `json:"error,omitempty"`
+	PollID   string    `json:"poll_id,omitempty"`
+}
+
+var (
+	// deliveries is a ring buffer, next is the slot written next
+	deliveries      []delivery
+	deliveriesNext  int
+	deliveriesFull  bool
+	deliveriesMutex sync.Mutex
+)
+
+// recordPollDelivery adds the outcome of a send to the ring buffer behind /notifications.
+// pollID is the cycle the send was made for, empty for sends outside of a poll.
+func recordPollDelivery(pollID, platform, target, payload string, err error) {
+	d := delivery{Time: time.Now(), Platform: platform, Target: redactTarget(target), Payload: payload, Status: "sent", PollID: pollID}
+	switch {
+	case err != nil:
+		d.Status = "failed"
+		d.Error = err.Error()
+	case currentConfig().DryRun:
+		d.Status = "dry_run"
+	}
+	notificationsCounter.WithLabelValues(platform, d.Status).Inc()
+
+	deliveriesMutex.Lock()
+	defer deliveriesMutex.Unlock()
+
+	size := currentConfig().NotificationLogSize
+	if len(deliveries) != size {
+		// First use or resized by a reload, keep the most recent entries
+		recent := recentDeliveries()
+		if len(recent) > size {
+			recent = recent[:size]
+		}
+		deliveries = make([]delivery, size)
+		for i := range recent {
+			deliveries[len(recent)-1-i] = recent[i]
+		}
+		deliveriesNext = len(recent) % size
+		deliveriesFull = len(recent) == size
+	}
+
+	deliveries[deliveriesNext] = d
+	deliveriesNext = (deliveriesNext + 1) % size
+	if deliveriesNext == 0 {
+		deliveriesFull = true
+	}
+}
+
+// recentDeliveries returns the buffered deliveries, newest first. The caller must hold deliveriesMutex.
+func recentDeliveries() []delivery {
+	n := deliveriesNext
+	if deliveriesFull {
+		n = len(deliveries)
+	}
+	recent := make([]delivery, 0, n)
+	for i := 1; i <= n; i++ {
+		recent = append(recent, deliveries[(deliveriesNext-i+len(deliveries))%len(deliveries)])
+	}
+	return recent
+}
+
+// redactTarget strips webhook URLs down to their host, the path often embeds a secret token
+func redactTarget(target string) string {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return target
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+func handleNotifications(w http.ResponseWriter, r *http.Request) {
+	deliveriesMutex.Lock()
+	recent := recentDeliveries()
+	deliveriesMutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recent)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// runDeviceAuth logs in with the OAuth device authorization flow and saves the token. It prints
+// where to enter the user code to w and polls Google until the code was confirmed, denied or
+// expired. It backs the -device-auth flag for servers without a browser.
+func runDeviceAuth(ctx context.Context, w io.Writer) error {
+	if cfg := currentConfig(); cfg.APIKey != "" || cfg.ServiceAccountFile != "" {
+		return errors.New("the device flow needs OAuth credentials, not an API key or service account")
+	}
+
+	auth, err := oauthConfig.DeviceAuth(oauthContext(ctx))
+	if err != nil {
+		return fmt.Errorf("requesting a device code: %w", err)
+	}
+	fmt.Fprintf(w, "Open %s on any device and enter the code %s\n", auth.VerificationURI, auth.UserCode)
+	if !auth.Expiry.IsZero() {
+		fmt.Fprintf(w, "The code expires at %s\n", auth.Expiry.Local().Format("15:04:05"))
+	}
+
+	// DeviceAccessToken waits the interval requested by Google between polls and gives up at expiry
+	tok, err := oauthConfig.DeviceAccessToken(oauthContext(ctx), auth)
+	if err != nil {
+		return fmt.Errorf("waiting for the device code to be confirmed: %w", err)
+	}
+	slog.Info("OAuth device login successful", "event", "oauth", "expiry", tok.Expiry, "refresh_token", tok.RefreshToken != "")
+	saveToken(tok)
+	return nil
+}
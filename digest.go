@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often pending changes are checked while digest_interval is unset, so changes queued
+// before a reload disabled the digest still go out
+const digestIdleCheck = time.Minute
+
+type digestKey struct {
+	channelID string
+	metric    string
+}
+
+var (
+	// digest holds one aggregated notification per channel and metric since the last flush
+	digest      = make(map[digestKey]Notification)
+	digestOrder []digestKey
+	digestSince time.Time
+	digestMutex sync.Mutex
+)
+
+func digestEnabled() bool {
+	return currentConfig().digestInterval > 0
+}
+
+// addToDigest merges n into the pending digest, keeping the count from before the first change
+func addToDigest(n Notification) {
+	digestMutex.Lock()
+	defer digestMutex.Unlock()
+
+	if len(digest) == 0 {
+		digestSince = time.Now()
+	}
+	key := digestKey{n.ChannelID, n.Metric}
+	if pending, ok := digest[key]; ok {
+		n.PreviousCount = pending.PreviousCount
+		n.Delta = countDelta(n.PreviousCount, n.Count)
+	} else {
+		digestOrder = append(digestOrder, key)
+	}
+	digest[key] = n
+	slog.Debug("Change added to digest", "event", "notify", "channel_id", n.ChannelID, "metric", n.Metric, "delta", n.Delta)
+}
+
+// runDigest flushes the digest every digest_interval until ctx is done
+func runDigest(ctx context.Context) {
+	for {
+		interval := currentConfig().digestInterval
+		if interval <= 0 {
+			interval = digestIdleCheck
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		flushDigest()
+	}
+}
+
+// flushDigest sends a single summary of the pending changes to the chat platforms and one
+// aggregated notification per channel and metric to the webhooks
+func flushDigest() {
+	digestMutex.Lock()
+	pending := make([]Notification, 0, len(digestOrder))
+	for _, key := range digestOrder {
+		pending = append(pending, digest[key])
+	}
+	since := digestSince
+	digest = make(map[digestKey]Notification)
+	digestOrder = nil
+	digestMutex.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+	// A digest spans several poll cycles, it is not correlated with any of them
+	ctx := context.Background()
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].ChannelTitle < pending[j].ChannelTitle })
+
+	period := time.Since(since).Round(time.Minute)
+	lines := make([]string, 0, len(pending))
+	for _, n := range pending {
+		if n.Delta == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: net %s %s over the last %s, now at %s",
+			n.ChannelTitle, n.SignedDelta(), n.Metric, period, formatCount(n.Count)))
+		n.PollID = ""
+		sendWebhookNotification(ctx, n)
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	slog.Info("Sending digest", "event", "notify", "changes", len(lines))
+	broadcastMessage(ctx, "digest", "Subscriber digest", strings.Join(lines, "\n"))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Sidebar color of the Discord embed (YouTube red)
+const discordEmbedColor = 0xFF0000
+
+// Requests per Discord notification when Discord keeps answering 429
+const discordAttempts = 2
+
+type discordEmbed struct {
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	URL         string             `json:"url,omitempty"`
+	Color       int                `json:"color"`
+	Timestamp   string             `json:"timestamp"`
+	Image       *discordEmbedImage `json:"image,omitempty"`
+}
+
+type discordEmbedImage struct {
+	URL string `json:"url"`
+}
+
+type discordPayload struct {
+	Embeds []discordEmbed `json:"embeds"`
+}
+
+func sendDiscordNotification(ctx context.Context, n Notification) error {
+	return postDiscordEmbed(ctx, discordEmbed{
+		Title:       n.Title(),
+		Description: n.Message(),
+		Color:       discordEmbedColor,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+func postDiscordEmbed(ctx context.Context, embed discordEmbed) (err error) {
+	embed.Description = truncateMessage(embed.Description, messageLimit("discord"))
+	body, _ := json.Marshal(discordPayload{Embeds: []discordEmbed{embed}})
+	defer func() {
+		recordPollDelivery(pollIDFrom(ctx), "discord", currentConfig().DiscordWebhookURL, string(body), err)
+	}()
+	if dryRun(ctx, "discord", currentConfig().DiscordWebhookURL, string(body)) {
+		return nil
+	}
+
+	// Discord answers 429 with the number of seconds to wait, retry once after that but never
+	// wait longer than api_timeout
+	for attempt := 1; attempt <= discordAttempts; attempt++ {
+		resp, err := httpClient.Post(currentConfig().DiscordWebhookURL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			slog.ErrorContext(ctx, "Error sending Discord notification", "event", "notify", "platform", "discord", "error", err)
+			return withKind(ErrTransient, err)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			var rateLimit struct {
+				RetryAfter float64 `json:"retry_after"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&rateLimit)
+			resp.Body.Close()
+			if attempt == discordAttempts {
+				break
+			}
+			wait := min(time.Duration(rateLimit.RetryAfter*float64(time.Second)), apiTimeout())
+			slog.WarnContext(ctx, "Discord rate limited, retrying", "event", "notify", "platform", "discord", "retry_after", rateLimit.RetryAfter, "wait", wait.String())
+			select {
+			case <-ctx.Done():
+				return withKind(ErrTransient, ctx.Err())
+			case <-time.After(wait):
+			}
+			continue
+		}
+		resp.Body.Close()
+
+		// Discord returns 204 No Content on success
+		if resp.StatusCode/100 != 2 {
+			slog.ErrorContext(ctx, "Unexpected status code from Discord", "event", "notify", "platform", "discord", "status", resp.StatusCode)
+			return &statusError{StatusCode: resp.StatusCode}
+		}
+		return nil
+	}
+	slog.ErrorContext(ctx, "Giving up on Discord notification after rate limit", "event", "notify", "platform", "discord")
+	return ErrRateLimited
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPostDiscordEmbedCapsRateLimitWait(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"retry_after": 60}`))
+	}))
+	defer server.Close()
+
+	cfg := useConfig(t, testConfigYAML+"discord_webhook_url: "+server.URL+"\napi_timeout: 50ms\n")
+	cfg.DryRun = false
+	useHTTPClient(t, cfg)
+	httpClient.Transport = server.Client().Transport
+
+	start := time.Now()
+	err := postDiscordEmbed(context.Background(), discordEmbed{Title: "Test"})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("postDiscordEmbed returned %v, want ErrRateLimited", err)
+	}
+	if n := requests.Load(); n != discordAttempts {
+		t.Errorf("Discord got %d requests, want %d", n, discordAttempts)
+	}
+	// One wait capped at api_timeout and none after the last attempt
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("postDiscordEmbed took %v, want the 60s retry_after capped at api_timeout", elapsed)
+	}
+}
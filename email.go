@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultSMTPPort = 587
+
+func emailConfigured() bool {
+	cfg := currentConfig()
+	return cfg.SMTPHost != "" && len(cfg.EmailTo) > 0
+}
+
+func sendEmailNotification(ctx context.Context, n Notification) error {
+	subject := fmt.Sprintf("%s: %s %s (%s)", n.ChannelTitle, formatCount(n.Count), n.Metric, n.SignedDelta())
+	body := fmt.Sprintf("%s\r\n\r\nCurrent: %s\r\nChange: %s\r\nPrevious: %s\r\n", n.Message(), formatCount(n.Count), n.SignedDelta(), formatCount(n.PreviousCount))
+	return sendEmail(ctx, subject, body)
+}
+
+// sendEmail delivers a plain text mail to every email_to recipient. The connection is
+// upgraded with STARTTLS whenever the server offers it, and the whole exchange must finish
+// within http_timeout so a hung server cannot stall the caller.
+func sendEmail(ctx context.Context, subject, body string) (err error) {
+	cfg := currentConfig()
+	port := cfg.SMTPPort
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	addr := net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+
+	headers := []string{
+		"From: " + cfg.EmailFrom,
+		"To: " + strings.Join(cfg.EmailTo, ", "),
+		// Channel titles may be localized or contain emoji, headers must stay ASCII
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	msg := strings.Join(headers, "\r\n") + "\r\n\r\n" + body
+	defer func() { recordPollDelivery(pollIDFrom(ctx), "email", strings.Join(cfg.EmailTo, ", "), msg, err) }()
+	if dryRun(ctx, "email", addr, msg) {
+		return nil
+	}
+
+	if err := sendMail(addr, cfg.SMTPHost, auth, cfg.EmailFrom, cfg.EmailTo, []byte(msg), cfg.httpTimeout); err != nil {
+		slog.ErrorContext(ctx, "Error sending email notification", "event", "notify", "platform", "email", "error", err)
+		return withKind(smtpKind(err), err)
+	}
+	return nil
+}
+
+// sendMail works like smtp.SendMail but gives up once timeout has passed, covering the dial
+// as well as every later read and write
+func sendMail(addr, host string, auth smtp.Auth, from string, to []string, msg []byte, timeout time.Duration) error {
+	conn, err := (&net.Dialer{Timeout: timeout}).Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(auth); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := c.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
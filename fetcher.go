@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/youtube/v3"
+)
+
+// ChannelStats is the data read for a channel on every poll
+type ChannelStats struct {
+	ChannelID       string `json:"channel_id"`
+	Title           string `json:"title,omitempty"` // empty unless the snippet part was requested
+	SubscriberCount uint64 `json:"subscriber_count"`
+	// HiddenSubscriberCount is set when the channel hides its subscribers, SubscriberCount is then meaningless
+	HiddenSubscriberCount bool   `json:"hidden_subscriber_count,omitempty"`
+	ViewCount             uint64 `json:"view_count"`
+	VideoCount            uint64 `json:"video_count"`
+	// CommentCount is 0 for the many channels that do not report it
+	CommentCount uint64 `json:"comment_count,omitempty"`
+	// UploadsPlaylistID is empty when the uploads playlist is unknown
+	UploadsPlaylistID string `json:"uploads_playlist_id,omitempty"`
+}
+
+// StatsFetcher is how the monitor loop reads from YouTube
+type StatsFetcher interface {
+	// FetchChannelStats returns the statistics of up to maxChannelsPerRequest channels keyed by
+	// channel ID. Channels that do not exist or hide their statistics are missing from the map.
+	FetchChannelStats(ctx context.Context, channels []ChannelConfig) (map[string]ChannelStats, error)
+	// FetchUploads returns a page of the uploads playlist starting at pageToken, empty for the
+	// first page, and the token of the next page, empty on the last one. YouTube usually lists
+	// uploads newest first but does not guarantee it.
+	FetchUploads(ctx context.Context, playlistID, pageToken string) ([]*youtube.PlaylistItem, string, error)
+	// FetchLiveStreams returns the broadcasts of the channel that are live right now
+	FetchLiveStreams(ctx context.Context, channelID string) ([]*youtube.SearchResult, error)
+	// ResolveChannelHandle returns the ID of the channel with the given @handle or legacy
+	// username, empty if there is none
+	ResolveChannelHandle(ctx context.Context, handle string) (string, error)
+}
+
+// youtubeFetcher implements StatsFetcher with the YouTube Data API
+type youtubeFetcher struct {
+	service *youtube.Service
+}
+
+// newYouTubeService creates the API client on top of client, talking to api_base_url when set
+func newYouTubeService(ctx context.Context, client *http.Client) (*youtube.Service, error) {
+	opts := []option.ClientOption{option.WithHTTPClient(client)}
+	if endpoint := currentConfig().apiBaseURL; endpoint != "" {
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+	return youtube.NewService(ctx, opts...)
+}
+
+func newYouTubeFetcher(service *youtube.Service) *youtubeFetcher {
+	return &youtubeFetcher{service: service}
+}
+
+func (f *youtubeFetcher) FetchChannelStats(ctx context.Context, channels []ChannelConfig) (map[string]ChannelStats, error) {
+	ids := make([]string, len(channels))
+	for i, channel := range channels {
+		ids[i] = channel.ChannelID
+	}
+
+	call := f.service.Channels.List(channelParts(channels)).Id(ids...).MaxResults(int64(len(ids)))
+	if language := currentConfig().Language; language != "" {
+		call = call.Hl(language)
+	}
+	start := time.Now()
+	callCtx, cancel := context.WithTimeout(ctx, apiTimeout())
+	defer cancel()
+	response, err := call.Context(callCtx).Do()
+	observeAPICall("channels.list", start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]ChannelStats, len(response.Items))
+	for _, item := range response.Items {
+		if item.Statistics == nil {
+			continue
+		}
+		s := ChannelStats{
+			ChannelID:             item.Id,
+			SubscriberCount:       item.Statistics.SubscriberCount,
+			HiddenSubscriberCount: item.Statistics.HiddenSubscriberCount,
+			ViewCount:             item.Statistics.ViewCount,
+			VideoCount:            item.Statistics.VideoCount,
+			CommentCount:          item.Statistics.CommentCount,
+			UploadsPlaylistID:     uploadsPlaylistID(item),
+		}
+		if item.Snippet != nil {
+			s.Title = item.Snippet.Title
+			// Without a localization for the requested language YouTube returns the default title
+			if item.Snippet.Localized != nil && item.Snippet.Localized.Title != "" {
+				s.Title = item.Snippet.Localized.Title
+			}
+		}
+		stats[item.Id] = s
+	}
+	return stats, nil
+}
+
+func (f *youtubeFetcher) FetchUploads(ctx context.Context, playlistID, pageToken string) ([]*youtube.PlaylistItem, string, error) {
+	call := f.service.PlaylistItems.List([]string{"snippet"}).PlaylistId(playlistID).MaxResults(uploadsPageSize)
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
+	start := time.Now()
+	callCtx, cancel := context.WithTimeout(ctx, apiTimeout())
+	defer cancel()
+	response, err := call.Context(callCtx).Do()
+	observeAPICall("playlistItems.list", start, err)
+	if err != nil {
+		return nil, "", err
+	}
+	return response.Items, response.NextPageToken, nil
+}
+
+func (f *youtubeFetcher) FetchLiveStreams(ctx context.Context, channelID string) ([]*youtube.SearchResult, error) {
+	start := time.Now()
+	callCtx, cancel := context.WithTimeout(ctx, apiTimeout())
+	defer cancel()
+	response, err := f.service.Search.List([]string{"snippet"}).ChannelId(channelID).EventType("live").Type("video").Context(callCtx).Do()
+	observeAPICall("search.list", start, err)
+	if err != nil {
+		return nil, err
+	}
+	return response.Items, nil
+}
+
+func (f *youtubeFetcher) ResolveChannelHandle(ctx context.Context, handle string) (string, error) {
+	lookups := []func(*youtube.ChannelsListCall) *youtube.ChannelsListCall{
+		func(call *youtube.ChannelsListCall) *youtube.ChannelsListCall { return call.ForHandle(handle) },
+		func(call *youtube.ChannelsListCall) *youtube.ChannelsListCall {
+			return call.ForUsername(strings.TrimPrefix(handle, "@"))
+		},
+	}
+	for _, lookup := range lookups {
+		start := time.Now()
+		callCtx, cancel := context.WithTimeout(ctx, apiTimeout())
+		response, err := lookup(f.service.Channels.List([]string{"id"})).Context(callCtx).Do()
+		cancel()
+		observeAPICall("channels.list", start, err)
+		if err != nil {
+			return "", err
+		}
+		if len(response.Items) > 0 {
+			return response.Items[0].Id, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Time given to pending notifications on shutdown, well inside the default 30s grace period
+// Kubernetes allows between SIGTERM and SIGKILL
+const notificationFlushTimeout = 10 * time.Second
+
+// flushNotifications sends the pending digest and waits for the Telegram queue to drain, both
+// within timeout. Messages still queued after timeout are logged as dropped.
+func flushNotifications(timeout time.Duration) {
+	digestSent := make(chan struct{})
+	go func() {
+		flushDigest()
+		close(digestSent)
+	}()
+
+	// The digest may queue Telegram messages, so the queue is awaited once it was sent
+	drained := make(chan struct{})
+	go func() {
+		<-digestSent
+		telegramPending.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		slog.Info("Pending notifications flushed")
+	case <-time.After(timeout):
+		select {
+		case <-digestSent:
+		default:
+			slog.Error("Digest was still being sent, its remaining deliveries are dropped", "event", "notify")
+		}
+		dropped := 0
+	drain:
+		for {
+			select {
+			case job := <-telegramQueue:
+				dropped++
+				slog.Error("Dropping queued Telegram message", "event", "notify", "platform", "telegram", "chats", len(job.chats), "text", job.text)
+			default:
+				break drain
+			}
+		}
+		slog.Error("Notification flush timed out", "event", "notify", "timeout", timeout.String(), "dropped", dropped)
+	}
+}
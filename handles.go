@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+)
+
+// channelHandles caches the channel ID of every resolved channel_handle. It is persisted to
+// channelHandles.json so a restart does not spend quota resolving the handles again.
+var (
+	channelHandles      map[string]string
+	channelHandlesMutex sync.Mutex
+)
+
+// resolvedChannelID returns the cached channel ID of handle, empty if it was never resolved
+func resolvedChannelID(handle string) string {
+	channelHandlesMutex.Lock()
+	defer channelHandlesMutex.Unlock()
+	if channelHandles == nil {
+		channelHandles = loadChannelHandles()
+	}
+	return channelHandles[handle]
+}
+
+// hasUnresolvedHandles reports whether a configured channel is only known by its handle
+func hasUnresolvedHandles() bool {
+	return slices.ContainsFunc(currentConfig().Channels, func(channel ChannelConfig) bool {
+		return channel.ChannelID == ""
+	})
+}
+
+// resolveChannelHandles looks up the ID of every channel configured by channel_handle and
+// installs a configuration with the IDs filled in. It fails on the first handle that does not
+// match a channel or matches one that is already configured.
+func resolveChannelHandles(ctx context.Context, fetcher StatsFetcher) error {
+	if !hasUnresolvedHandles() {
+		return nil
+	}
+
+	// The IDs only go to the cache here, the API calls may race with a SIGHUP reload
+	for _, channel := range currentConfig().Channels {
+		if channel.ChannelID != "" || resolvedChannelID(channel.Handle) != "" {
+			continue
+		}
+		id, err := fetcher.ResolveChannelHandle(ctx, channel.Handle)
+		if err != nil {
+			return fmt.Errorf("resolving channel_handle %s: %w", channel.Handle, err)
+		}
+		if id == "" {
+			return fmt.Errorf("channel_handle %s does not match any YouTube channel", channel.Handle)
+		}
+		slog.Info("Resolved channel handle", "handle", channel.Handle, "channel_id", id)
+
+		channelHandlesMutex.Lock()
+		channelHandles[channel.Handle] = id
+		saveChannelHandles(channelHandles)
+		channelHandlesMutex.Unlock()
+	}
+	return applyResolvedHandles()
+}
+
+// applyResolvedHandles fills in the cached IDs of the running configuration. It is re-read under
+// configMutex so a configuration installed by a reload meanwhile is not replaced by an old one.
+func applyResolvedHandles() error {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	cfg := *config
+	cfg.Channels = slices.Clone(cfg.Channels)
+	configured := make(map[string]bool)
+	for _, channel := range cfg.Channels {
+		if channel.ChannelID != "" {
+			configured[channel.ChannelID] = true
+		}
+	}
+	for i, channel := range cfg.Channels {
+		if channel.ChannelID != "" {
+			continue
+		}
+		// A handle added by a reload meanwhile is resolved with the next poll
+		id := resolvedChannelID(channel.Handle)
+		if id == "" {
+			continue
+		}
+		if configured[id] {
+			return fmt.Errorf("channel_handle %s resolves to channel %s, which is already configured", channel.Handle, id)
+		}
+		configured[id] = true
+		cfg.Channels[i].ChannelID = id
+	}
+	config = &cfg
+	return nil
+}
+
+func loadChannelHandles() map[string]string {
+	handles := make(map[string]string)
+	data, err := os.ReadFile("channelHandles.json")
+	if err != nil {
+		return handles
+	}
+	if err := json.Unmarshal(data, &handles); err != nil {
+		slog.Error("Error decoding channelHandles.json", "error", err)
+	}
+	return handles
+}
+
+func saveChannelHandles(handles map[string]string) {
+	data, _ := json.Marshal(handles)
+	if err := writeFileAtomic("channelHandles.json", data, 0644); err != nil {
+		slog.Error("Error saving channelHandles.json", "error", err)
+	}
+}
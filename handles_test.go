@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// resetChannelHandles starts the test with an empty handle cache in a temporary directory
+func resetChannelHandles(t *testing.T) {
+	t.Helper()
+	useTempDir(t)
+	channelHandlesMutex.Lock()
+	channelHandles = nil
+	channelHandlesMutex.Unlock()
+	t.Cleanup(func() {
+		channelHandlesMutex.Lock()
+		channelHandles = nil
+		channelHandlesMutex.Unlock()
+	})
+}
+
+const handlesConfigYAML = `
+api_key: test
+webhook_url: https://example.com/hook
+dry_run: true
+channels:
+  - channel_id: UCaaaaaaaaaaaaaaaaaaaaaa
+  - channel_handle: "@second"
+`
+
+func TestResolveChannelHandlesKeepsReloadedConfig(t *testing.T) {
+	resetChannelHandles(t)
+	useConfig(t, handlesConfigYAML)
+
+	// A SIGHUP reload lands while the handle is being resolved
+	fetcher := &fakeFetcher{resolve: func(handle string) (string, error) {
+		reloaded := &Config{}
+		if err := decodeConfig("config.yaml", []byte(handlesConfigYAML+"    label: Reloaded\n"), reloaded); err != nil {
+			t.Fatal(err)
+		}
+		if err := reloaded.validate(); err != nil {
+			t.Fatal(err)
+		}
+		setConfig(reloaded)
+		return "UCbbbbbbbbbbbbbbbbbbbbbb", nil
+	}}
+	if err := resolveChannelHandles(context.Background(), fetcher); err != nil {
+		t.Fatalf("resolveChannelHandles: %v", err)
+	}
+
+	channel := currentConfig().Channels[1]
+	if channel.Label != "Reloaded" {
+		t.Errorf("the reloaded configuration was replaced, label is %q", channel.Label)
+	}
+	if channel.ChannelID != "UCbbbbbbbbbbbbbbbbbbbbbb" {
+		t.Errorf("channel_handle @second resolved to %q, want UCbbbbbbbbbbbbbbbbbbbbbb", channel.ChannelID)
+	}
+	if id := resolvedChannelID("@second"); id != "UCbbbbbbbbbbbbbbbbbbbbbb" {
+		t.Errorf("cache holds %q for @second", id)
+	}
+}
+
+func TestResolveChannelHandlesRejectsConfiguredChannel(t *testing.T) {
+	resetChannelHandles(t)
+	cfg := useConfig(t, handlesConfigYAML)
+
+	fetcher := &fakeFetcher{resolve: func(handle string) (string, error) { return "UCaaaaaaaaaaaaaaaaaaaaaa", nil }}
+	err := resolveChannelHandles(context.Background(), fetcher)
+	if err == nil || !strings.Contains(err.Error(), "already configured") {
+		t.Fatalf("resolveChannelHandles returned %v, want the duplicate channel rejected", err)
+	}
+	if currentConfig() != cfg || currentConfig().Channels[1].ChannelID != "" {
+		t.Error("the configuration was changed although a handle was rejected")
+	}
+}
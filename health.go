@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The monitor is reported stale after missing this many poll intervals, unless a backoff
+// planned the next poll even later
+const unhealthyPollMultiple = 3
+
+// Time a poll may take beyond the planned delay before the monitor counts as wedged
+const minMonitorGrace = 5 * time.Minute
+
+var (
+	startTime    = time.Now()
+	lastPollTime time.Time
+	// monitorHeartbeat is when the monitor loop last started waiting, it should be back by monitorDueBy
+	monitorHeartbeat time.Time
+	monitorDueBy     time.Time
+	lastPollMutex    sync.Mutex
+)
+
+// recordMonitorHeartbeat notes that the monitor loop is alive and about to wait for delay
+func recordMonitorHeartbeat(delay time.Duration) {
+	lastPollMutex.Lock()
+	monitorHeartbeat = time.Now()
+	monitorDueBy = monitorHeartbeat.Add(delay + max(minMonitorGrace, pollInterval()))
+	lastPollMutex.Unlock()
+}
+
+func recordSuccessfulPoll() {
+	lastPollMutex.Lock()
+	lastPollTime = time.Now()
+	lastPollMutex.Unlock()
+}
+
+type healthResponse struct {
+	Status           string           `json:"status"`
+	TokenLoaded      bool             `json:"token_loaded"`
+	LastPoll         *time.Time       `json:"last_poll,omitempty"`
+	MonitorHeartbeat *time.Time       `json:"monitor_heartbeat,omitempty"`
+	SubscriberCounts map[string]int64 `json:"subscriber_counts"`
+}
+
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	tokenMutex.Lock()
+	resp := healthResponse{Status: "ok", TokenLoaded: haveCredentials()}
+	tokenMutex.Unlock()
+
+	lastPollMutex.Lock()
+	since := startTime
+	if !lastPollTime.IsZero() {
+		t := lastPollTime
+		resp.LastPoll = &t
+		since = t
+	}
+	var wedged bool
+	var staleAfter time.Time
+	if !monitorHeartbeat.IsZero() {
+		t := monitorHeartbeat
+		resp.MonitorHeartbeat = &t
+		wedged = time.Now().After(monitorDueBy)
+	}
+	// Without credentials no poll can succeed, a restart would only lose the pending login
+	if resp.TokenLoaded {
+		// A quota or error backoff plans the next poll later than the usual intervals
+		staleAfter = since.Add(unhealthyPollMultiple * pollInterval())
+		if monitorDueBy.After(staleAfter) {
+			staleAfter = monitorDueBy
+		}
+	}
+	lastPollMutex.Unlock()
+
+	latestCountMutex.Lock()
+	resp.SubscriberCounts = make(map[string]int64, len(latestCount))
+	for id, count := range latestCount {
+		resp.SubscriberCounts[id] = count
+	}
+	latestCountMutex.Unlock()
+
+	status := http.StatusOK
+	switch {
+	case wedged || !monitorRunning.Load():
+		resp.Status = "monitor_stalled"
+		status = http.StatusServiceUnavailable
+	case !staleAfter.IsZero() && time.Now().After(staleAfter):
+		resp.Status = "stale"
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+type readyResponse struct {
+	Ready  bool   `json:"ready"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// handleReady reports ready once a token is loaded and a poll has succeeded
+func handleReady(w http.ResponseWriter, r *http.Request) {
+	resp := readyResponse{Ready: true}
+
+	tokenMutex.Lock()
+	hasToken := haveCredentials()
+	tokenMutex.Unlock()
+
+	lastPollMutex.Lock()
+	polled := !lastPollTime.IsZero()
+	lastPollMutex.Unlock()
+
+	switch {
+	case !hasToken:
+		resp = readyResponse{Reason: "awaiting OAuth token"}
+	case !polled:
+		resp = readyResponse{Reason: "awaiting first poll"}
+	}
+
+	status := http.StatusOK
+	if !resp.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
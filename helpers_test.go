@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// testChannelID is the channel of testConfigYAML
+const testChannelID = "UCxxxxxxxxxxxxxxxxxxxxxx"
+
+// testConfigYAML is the smallest valid configuration, it reads one channel with an API key and
+// only logs notifications
+const testConfigYAML = `
+api_key: test
+channel_id: UCxxxxxxxxxxxxxxxxxxxxxx
+webhook_url: https://example.com/hook
+dry_run: true
+`
+
+// useTempDir runs the rest of the test in an empty directory, the state files are kept in the
+// working directory
+func useTempDir(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// useConfig makes the configuration decoded from yaml current until the test ends
+func useConfig(t *testing.T, yaml string) *Config {
+	t.Helper()
+	cfg := &Config{}
+	if err := decodeConfig("config.yaml", []byte(yaml), cfg); err != nil {
+		t.Fatalf("decoding config: %v", err)
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("validating config: %v", err)
+	}
+	previous := currentConfig()
+	setConfig(cfg)
+	t.Cleanup(func() { setConfig(previous) })
+	return cfg
+}
+
+// fakeFetcher is a StatsFetcher returning scripted responses
+type fakeFetcher struct {
+	// stats is called for every FetchChannelStats call
+	stats func(channels []ChannelConfig) (map[string]ChannelStats, error)
+	// uploads holds the pages of the uploads playlist, the page token is the page index
+	uploads [][]*youtube.PlaylistItem
+	// uploadCalls counts FetchUploads calls
+	uploadCalls int
+	// resolve answers ResolveChannelHandle calls, every handle is unknown without it
+	resolve func(handle string) (string, error)
+}
+
+func (f *fakeFetcher) FetchChannelStats(ctx context.Context, channels []ChannelConfig) (map[string]ChannelStats, error) {
+	if f.stats == nil {
+		return map[string]ChannelStats{}, nil
+	}
+	return f.stats(channels)
+}
+
+func (f *fakeFetcher) FetchUploads(ctx context.Context, playlistID, pageToken string) ([]*youtube.PlaylistItem, string, error) {
+	f.uploadCalls++
+	page := 0
+	if pageToken != "" {
+		page = int(pageToken[0] - '0')
+	}
+	if page >= len(f.uploads) {
+		return nil, "", nil
+	}
+	next := ""
+	if page+1 < len(f.uploads) {
+		next = string(rune('0' + page + 1))
+	}
+	return f.uploads[page], next, nil
+}
+
+func (f *fakeFetcher) FetchLiveStreams(ctx context.Context, channelID string) ([]*youtube.SearchResult, error) {
+	return nil, nil
+}
+
+func (f *fakeFetcher) ResolveChannelHandle(ctx context.Context, handle string) (string, error) {
+	if f.resolve == nil {
+		return "", nil
+	}
+	return f.resolve(handle)
+}
+
+// connectTo returns a fetcherFactory always handing out fetcher
+func connectTo(fetcher StatsFetcher) fetcherFactory {
+	return func(ctx context.Context) (StatsFetcher, error) { return fetcher, nil }
+}
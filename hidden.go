@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+)
+
+// hiddenSubscribers holds the channels currently hiding their subscriber count, guarded by latestCountMutex
+var hiddenSubscribers = make(map[string]bool)
+
+// trackHiddenSubscribers records whether the channel hides its subscriber count and reports it.
+// The change is logged once in each direction instead of on every poll.
+func trackHiddenSubscribers(ctx context.Context, channel ChannelConfig, hidden bool) bool {
+	latestCountMutex.Lock()
+	defer latestCountMutex.Unlock()
+
+	switch {
+	case hidden && !hiddenSubscribers[channel.ChannelID]:
+		slog.WarnContext(ctx, "Channel hides its subscriber count, skipping subscriber notifications", "event", "poll", "channel_id", channel.ChannelID)
+		hiddenSubscribers[channel.ChannelID] = true
+	case !hidden && hiddenSubscribers[channel.ChannelID]:
+		slog.InfoContext(ctx, "Channel subscriber count is visible again", "event", "poll", "channel_id", channel.ChannelID)
+		delete(hiddenSubscribers, channel.ChannelID)
+	}
+	return hidden
+}
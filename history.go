@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// historyDB is nil unless db_path is configured
+var historyDB *sql.DB
+
+// HistoryRow is the channel statistics recorded by a single poll
+type HistoryRow struct {
+	Timestamp       time.Time `json:"timestamp"`
+	ChannelID       string    `json:"channel_id"`
+	SubscriberCount uint64    `json:"subscriber_count"`
+	ViewCount       uint64    `json:"view_count"`
+	VideoCount      uint64    `json:"video_count"`
+}
+
+func openHistory(path string) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS history (
+		timestamp INTEGER NOT NULL,
+		channel_id TEXT NOT NULL,
+		subscriber_count INTEGER NOT NULL,
+		view_count INTEGER NOT NULL,
+		video_count INTEGER NOT NULL
+	)`)
+	if err == nil {
+		_, err = db.Exec(`CREATE INDEX IF NOT EXISTS history_channel_timestamp ON history (channel_id, timestamp)`)
+	}
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	historyDB = db
+	return nil
+}
+
+func recordHistory(stats ChannelStats) {
+	if historyDB == nil {
+		return
+	}
+
+	_, err := historyDB.Exec(`INSERT INTO history (timestamp, channel_id, subscriber_count, view_count, video_count) VALUES (?, ?, ?, ?, ?)`,
+		time.Now().Unix(), stats.ChannelID, int64(stats.SubscriberCount), int64(stats.ViewCount), int64(stats.VideoCount))
+	if err != nil {
+		slog.Error("Error recording history", "channel_id", stats.ChannelID, "error", err)
+	}
+}
+
+// latestHistoryCounts returns the most recently recorded subscriber count of every channel in the history
+func latestHistoryCounts() (map[string]int64, error) {
+	counts := make(map[string]int64)
+	if historyDB == nil {
+		return counts, nil
+	}
+
+	rows, err := historyDB.Query(`SELECT channel_id, subscriber_count FROM history
+		WHERE rowid IN (SELECT MAX(rowid) FROM history GROUP BY channel_id)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var channelID string
+		var count int64
+		if err := rows.Scan(&channelID, &count); err != nil {
+			return nil, err
+		}
+		counts[channelID] = count
+	}
+	return counts, rows.Err()
+}
+
+// GetHistory returns the rows recorded for a channel since the given time, oldest first
+func GetHistory(channelID string, since time.Time) ([]HistoryRow, error) {
+	if historyDB == nil {
+		return nil, nil
+	}
+
+	rows, err := historyDB.Query(`SELECT timestamp, channel_id, subscriber_count, view_count, video_count FROM history
+		WHERE channel_id = ? AND timestamp >= ? ORDER BY timestamp`, channelID, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []HistoryRow
+	for rows.Next() {
+		var row HistoryRow
+		var timestamp, subscribers, views, videos int64
+		if err := rows.Scan(&timestamp, &row.ChannelID, &subscribers, &views, &videos); err != nil {
+			return nil, err
+		}
+		row.Timestamp = time.Unix(timestamp, 0)
+		row.SubscriberCount = uint64(subscribers)
+		row.ViewCount = uint64(views)
+		row.VideoCount = uint64(videos)
+		history = append(history, row)
+	}
+	return history, rows.Err()
+}
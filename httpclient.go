@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const defaultHTTPTimeout = 10 * time.Second
+
+// defaultUserAgent identifies this app when user_agent is not configured
+var defaultUserAgent = "youtube-notification/" + version
+
+// httpClient is shared by every outbound call, notifications as well as Google APIs
+var httpClient = &http.Client{Timeout: defaultHTTPTimeout}
+
+// webhookClient posts webhooks, it is httpClient unless webhook_ca_file adds trusted CAs
+var webhookClient = httpClient
+
+// configureHTTPClient applies http_timeout, proxy_url and user_agent to httpClient. Without proxy_url the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are honored.
+func configureHTTPClient(cfg *Config) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if cfg.proxyURL != nil {
+		transport.Proxy = http.ProxyURL(cfg.proxyURL)
+	}
+	httpClient.Transport = userAgentTransport{base: transport}
+	httpClient.Timeout = cfg.httpTimeout
+
+	webhookClient = httpClient
+	if cfg.webhookRootCAs != nil {
+		webhookTransport := transport.Clone()
+		webhookTransport.TLSClientConfig = &tls.Config{RootCAs: cfg.webhookRootCAs}
+		webhookClient = &http.Client{Transport: userAgentTransport{base: webhookTransport}, Timeout: cfg.httpTimeout}
+	}
+}
+
+// loadCAFile returns the system roots plus the PEM certificates in path
+func loadCAFile(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		slog.Warn("Unable to load the system certificate pool, trusting only the configured CAs", "error", err)
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM encoded certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// userAgentTransport sets the configured User-Agent on every outbound request
+type userAgentTransport struct {
+	base http.RoundTripper
+}
+
+func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", currentConfig().UserAgent)
+	return t.base.RoundTrip(req)
+}
+
+// oauthContext makes the oauth2 package use httpClient for token exchange and refresh
+func oauthContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+}
+
+// youtubeClient returns an authorized client for tok that shares the transport and timeout of httpClient
+func youtubeClient(ctx context.Context, tok *oauth2.Token) *http.Client {
+	client := oauthConfig.Client(oauthContext(ctx), tok)
+	client.Timeout = httpClient.Timeout
+	return client
+}
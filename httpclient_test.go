@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useHTTPClient applies the HTTP settings of cfg until the test ends
+func useHTTPClient(t *testing.T, cfg *Config) {
+	t.Helper()
+	transport, timeout, webhook := httpClient.Transport, httpClient.Timeout, webhookClient
+	configureHTTPClient(cfg)
+	t.Cleanup(func() {
+		httpClient.Transport, httpClient.Timeout, webhookClient = transport, timeout, webhook
+	})
+}
+
+func TestHTTPTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cfg := useConfig(t, `
+api_key: test
+channel_id: UCxxxxxxxxxxxxxxxxxxxxxx
+webhook_url: `+server.URL+`
+http_timeout: 50ms
+`)
+	useHTTPClient(t, cfg)
+
+	start := time.Now()
+	err := postWebhook(context.Background(), WebhookConfig{URL: server.URL}, []byte(`{}`))
+	if err == nil {
+		t.Fatal("postWebhook succeeded against a server slower than http_timeout")
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("postWebhook error is %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("postWebhook returned after %v, want about the 50ms http_timeout", elapsed)
+	}
+}
+
+// recordingProxy is a forward proxy that answers plain HTTP requests itself and refuses CONNECT
+type recordingProxy struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (p *recordingProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.requests = append(p.requests, r.Method+" "+r.Host)
+	p.mu.Unlock()
+	if r.Method == http.MethodConnect {
+		http.Error(w, "tunnels are not allowed", http.StatusForbidden)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *recordingProxy) seen() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.requests...)
+}
+
+func TestProxyURL(t *testing.T) {
+	proxy := &recordingProxy{}
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+
+	cfg := useConfig(t, `
+api_key: test
+channel_id: UCxxxxxxxxxxxxxxxxxxxxxx
+webhook_url: http://example.invalid/hook
+proxy_url: `+server.URL+`
+`)
+	useHTTPClient(t, cfg)
+
+	if err := postWebhook(context.Background(), WebhookConfig{URL: "http://example.invalid/hook"}, []byte(`{}`)); err != nil {
+		t.Fatalf("postWebhook through proxy_url: %v", err)
+	}
+	if seen := proxy.seen(); len(seen) != 1 || seen[0] != "POST example.invalid" {
+		t.Errorf("proxy saw %q, want [\"POST example.invalid\"]", seen)
+	}
+}
+
+// TestProxyFromEnvironment runs in a child process, net/http reads the proxy variables only once
+// per process
+func TestProxyFromEnvironment(t *testing.T) {
+	if os.Getenv("YTN_PROXY_CHILD") != "" {
+		cfg := useConfig(t, testConfigYAML+"http_timeout: 5s\n")
+		cfg.DryRun = false
+		useHTTPClient(t, cfg)
+		postWebhook(context.Background(), WebhookConfig{URL: "https://example.invalid/hook"}, []byte(`{}`))
+		return
+	}
+
+	proxy := &recordingProxy{}
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestProxyFromEnvironment$")
+	cmd.Env = append(os.Environ(), "YTN_PROXY_CHILD=1", "HTTPS_PROXY="+server.URL, "NO_PROXY=")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("child process: %v\n%s", err, out)
+	}
+	if seen := proxy.seen(); len(seen) != 1 || seen[0] != "CONNECT example.invalid:443" {
+		t.Errorf("proxy saw %q, want [\"CONNECT example.invalid:443\"]", seen)
+	}
+}
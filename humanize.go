@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// humanizeCount formats n with thousands separators, e.g. 1234567 becomes "1,234,567"
+func humanizeCount(n uint64) string {
+	digits := strconv.FormatUint(n, 10)
+	if len(digits) <= 3 {
+		return digits
+	}
+
+	var b strings.Builder
+	b.Grow(len(digits) + (len(digits)-1)/3)
+	lead := len(digits) % 3
+	if lead == 0 {
+		lead = 3
+	}
+	b.WriteString(digits[:lead])
+	for i := lead; i < len(digits); i += 3 {
+		b.WriteByte(',')
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
+
+// compactCount formats n with a unit suffix and one decimal, e.g. 1234567 becomes "1.2M"
+func compactCount(n uint64) string {
+	units := []struct {
+		value  uint64
+		suffix string
+	}{
+		{1e18, "E"},
+		{1e15, "P"},
+		{1e12, "T"},
+		{1e9, "B"},
+		{1e6, "M"},
+		{1e3, "K"},
+	}
+	for _, unit := range units {
+		if n >= unit.value {
+			// Truncate rather than round so 999,999 is not shown as 1000.0K
+			tenths := n / (unit.value / 10)
+			s := strconv.FormatUint(tenths/10, 10)
+			if frac := tenths % 10; frac != 0 {
+				s += "." + strconv.FormatUint(frac, 10)
+			}
+			return s + unit.suffix
+		}
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+// formatCount formats a count for notifications according to compact_counts
+func formatCount(n uint64) string {
+	if cfg := currentConfig(); cfg != nil && cfg.CompactCounts {
+		return compactCount(n)
+	}
+	return humanizeCount(n)
+}
+
+// formatDelta formats a signed change with an explicit sign, e.g. "+1,234" or "-1"
+func formatDelta(d int64) string {
+	if d < 0 {
+		// Negate in uint64 so math.MinInt64 does not overflow
+		return "-" + formatCount(uint64(-(d+1))+1)
+	}
+	return "+" + formatCount(uint64(d))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHumanizeCount(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1,000"},
+		{1001, "1,001"},
+		{999999, "999,999"},
+		{1000000, "1,000,000"},
+		{1234567, "1,234,567"},
+		{math.MaxUint64 - 1, "18,446,744,073,709,551,614"},
+		{math.MaxUint64, "18,446,744,073,709,551,615"},
+	}
+	for _, tt := range tests {
+		if got := humanizeCount(tt.n); got != tt.want {
+			t.Errorf("humanizeCount(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCompactCount(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1K"},
+		{1050, "1K"},
+		{1100, "1.1K"},
+		{999999, "999.9K"},
+		{1000000, "1M"},
+		{1234567, "1.2M"},
+		{999999999, "999.9M"},
+		{1e9, "1B"},
+		{1e12, "1T"},
+		{1e15, "1P"},
+		{1e18, "1E"},
+		{math.MaxUint64, "18.4E"},
+	}
+	for _, tt := range tests {
+		if got := compactCount(tt.n); got != tt.want {
+			t.Errorf("compactCount(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDelta(t *testing.T) {
+	useTempDir(t)
+	useConfig(t, testConfigYAML)
+
+	tests := []struct {
+		d    int64
+		want string
+	}{
+		{0, "+0"},
+		{1, "+1"},
+		{-1, "-1"},
+		{999, "+999"},
+		{-1000, "-1,000"},
+		{math.MaxInt64, "+9,223,372,036,854,775,807"},
+		{math.MinInt64 + 1, "-9,223,372,036,854,775,807"},
+		{math.MinInt64, "-9,223,372,036,854,775,808"},
+	}
+	for _, tt := range tests {
+		if got := formatDelta(tt.d); got != tt.want {
+			t.Errorf("formatDelta(%d) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDeltaCompact(t *testing.T) {
+	useTempDir(t)
+	useConfig(t, testConfigYAML+"compact_counts: true\n")
+
+	tests := []struct {
+		d    int64
+		want string
+	}{
+		{999, "+999"},
+		{-1000, "-1K"},
+		{math.MinInt64, "-9.2E"},
+	}
+	for _, tt := range tests {
+		if got := formatDelta(tt.d); got != tt.want {
+			t.Errorf("formatDelta(%d) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// configTemplate documents every configuration field, -init writes it to config.yaml
+//
+//go:embed config.template.yaml
+var configTemplate []byte
+
+// writeConfigTemplate creates path from configTemplate, refusing to replace an existing file
+func writeConfigTemplate(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s already exists, not overwriting it", path)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(configTemplate); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
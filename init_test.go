@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// yamlKeys adds the yaml keys of the fields of t and of the structs nested in them to keys,
+// mapped to the path of the first field using the key
+func yamlKeys(t reflect.Type, prefix string, keys map[string]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		if _, ok := keys[name]; !ok {
+			keys[name] = prefix + name
+		}
+		typ := field.Type
+		for typ.Kind() == reflect.Slice || typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Map {
+			typ = typ.Elem()
+		}
+		if typ.Kind() == reflect.Struct {
+			yamlKeys(typ, prefix+name+".", keys)
+		}
+	}
+}
+
+func TestConfigTemplateListsEveryKey(t *testing.T) {
+	keys := make(map[string]string)
+	for _, typ := range []reflect.Type{
+		reflect.TypeOf(Config{}),
+		reflect.TypeOf(ChannelConfig{}),
+		reflect.TypeOf(ChatConfig{}),
+		reflect.TypeOf(BotConfig{}),
+	} {
+		yamlKeys(typ, typ.Name()+".", keys)
+	}
+
+	template := string(configTemplate)
+	for key, path := range keys {
+		// Keys are set or shown commented out, possibly as a list item
+		if !regexp.MustCompile(`(?m)^[ \t#-]*` + regexp.QuoteMeta(key) + `:`).MatchString(template) {
+			t.Errorf("config.template.yaml does not document %s (%s)", key, path)
+		}
+	}
+}
+
+// templateDefault returns the value config.template.yaml documents for the top-level key
+func templateDefault(t *testing.T, key string) string {
+	t.Helper()
+	m := regexp.MustCompile(`(?m)^# ` + regexp.QuoteMeta(key) + `: (.*)$`).FindStringSubmatch(string(configTemplate))
+	if m == nil {
+		t.Fatalf("config.template.yaml has no commented default for %s", key)
+	}
+	return strings.Trim(m[1], `"`)
+}
+
+func TestConfigTemplateDefaults(t *testing.T) {
+	cfg := useConfig(t, testConfigYAML)
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"poll_interval", cfg.pollInterval.String()},
+		{"min_poll_interval", cfg.minPollInterval.String()},
+		{"poll_jitter", cfg.pollJitter.String()},
+		{"poll_concurrency", strconv.Itoa(cfg.PollConcurrency)},
+		{"api_timeout", apiTimeout().String()},
+		{"token_refresh_skew", cfg.tokenRefreshSkew.String()},
+		{"http_timeout", cfg.httpTimeout.String()},
+		{"webhook_max_age", cfg.webhookMaxAge.String()},
+		{"daily_quota", strconv.Itoa(defaultDailyQuota)},
+		{"max_backoff", strconv.Itoa(int(defaultMaxBackoff / time.Second))},
+		{"telegram_rate_limit", strconv.FormatFloat(cfg.TelegramRateLimit, 'f', -1, 64)},
+		{"notification_log_size", strconv.Itoa(cfg.NotificationLogSize)},
+		{"listen_addr", cfg.ListenAddr},
+		{"min_change_mode", cfg.MinChangeMode},
+		{"delta_windows", "[]"},
+		{"milestones", "[]"},
+	}
+	for _, tt := range tests {
+		// Durations are compared parsed, the template writes 1m where String gives 1m0s
+		got := templateDefault(t, tt.key)
+		if d, err := time.ParseDuration(got); err == nil {
+			got = d.String()
+		}
+		if got != tt.want {
+			t.Errorf("config.template.yaml documents %s: %s, the default is %s", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// liveStream is the broadcast a channel was last seen live with
+type liveStream struct {
+	VideoID string `json:"video_id"`
+	Title   string `json:"title"`
+}
+
+var (
+	liveStreams      map[string]liveStream
+	liveStreamsMutex sync.Mutex
+)
+
+// checkLivestream notifies when the channel starts a live stream and, with notify_livestream_end,
+// when it stops. Each check costs a search.list call, so it only runs with watch_livestreams.
+func checkLivestream(ctx context.Context, fetcher StatsFetcher, channel ChannelConfig) {
+	if !currentConfig().WatchLivestreams {
+		return
+	}
+
+	results, err := fetcher.FetchLiveStreams(ctx, channel.ChannelID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching live streams", "event", "poll", "channel_id", channel.ChannelID, "error", err)
+		return
+	}
+
+	liveStreamsMutex.Lock()
+	defer liveStreamsMutex.Unlock()
+
+	if liveStreams == nil {
+		liveStreams = loadLiveStreams()
+	}
+
+	previous, wasLive := liveStreams[channel.ChannelID]
+	var current *youtube.SearchResult
+	if len(results) > 0 && results[0].Id != nil && results[0].Snippet != nil {
+		current = results[0]
+	}
+
+	switch {
+	case current != nil && (!wasLive || previous.VideoID != current.Id.VideoId):
+		liveStreams[channel.ChannelID] = liveStream{VideoID: current.Id.VideoId, Title: current.Snippet.Title}
+		saveLiveStreams(liveStreams)
+		sendLiveNotification(ctx, channel, current)
+	case current == nil && wasLive:
+		delete(liveStreams, channel.ChannelID)
+		saveLiveStreams(liveStreams)
+		if currentConfig().NotifyLivestreamEnd {
+			sendLiveEndedNotification(ctx, channel, previous)
+		}
+	}
+}
+
+func sendLiveNotification(ctx context.Context, channel ChannelConfig, video *youtube.SearchResult) {
+	slog.InfoContext(ctx, "Live stream started", "event", "livestream", "channel_id", channel.ChannelID, "video_id", video.Id.VideoId)
+
+	url := "https://www.youtube.com/watch?v=" + video.Id.VideoId
+	text := fmt.Sprintf("🔴 *%s* is live: [%s](%s)",
+		escapeMarkdownV2(channel.Name()), escapeMarkdownV2(video.Snippet.Title), escapeMarkdownV2URL(url))
+	sendTelegramMessage(ctx, text, "MarkdownV2")
+
+	if currentConfig().DiscordWebhookURL != "" {
+		postDiscordEmbed(ctx, discordEmbed{
+			Title:       video.Snippet.Title,
+			Description: fmt.Sprintf("%s is live", channel.Name()),
+			URL:         url,
+			Color:       discordEmbedColor,
+			Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+func sendLiveEndedNotification(ctx context.Context, channel ChannelConfig, stream liveStream) {
+	slog.InfoContext(ctx, "Live stream ended", "event", "livestream", "channel_id", channel.ChannelID, "video_id", stream.VideoID)
+
+	text := fmt.Sprintf("%s ended the live stream %q", channel.Name(), stream.Title)
+	sendTelegramMessage(ctx, text, "")
+
+	if currentConfig().DiscordWebhookURL != "" {
+		postDiscordEmbed(ctx, discordEmbed{
+			Title:       stream.Title,
+			Description: fmt.Sprintf("%s ended the live stream", channel.Name()),
+			URL:         "https://www.youtube.com/watch?v=" + stream.VideoID,
+			Color:       discordEmbedColor,
+			Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+func loadLiveStreams() map[string]liveStream {
+	streams := make(map[string]liveStream)
+	data, err := os.ReadFile("liveStreams.json")
+	if err != nil {
+		return streams
+	}
+	if err := json.Unmarshal(data, &streams); err != nil {
+		slog.Error("Error decoding liveStreams.json", "error", err)
+	}
+	return streams
+}
+
+func saveLiveStreams(streams map[string]liveStream) {
+	data, _ := json.Marshal(streams)
+	if err := writeFileAtomic("liveStreams.json", data, 0644); err != nil {
+		slog.Error("Error saving liveStreams.json", "error", err)
+	}
+}
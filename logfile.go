@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// rotatingFile appends to a log file and, when maxSize is set, moves it to <path>.1 once it
+// would grow past maxSize bytes. Only one rotated file is kept.
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+}
+
+var (
+	// logFiles keeps files open across configuration reloads, keyed by path
+	logFiles      = make(map[string]*rotatingFile)
+	logFilesMutex sync.Mutex
+)
+
+// openLogFile returns the writer for path, reusing the one opened by an earlier configuration
+func openLogFile(path string, maxSize int64) (io.Writer, error) {
+	logFilesMutex.Lock()
+	defer logFilesMutex.Unlock()
+
+	if f, ok := logFiles[path]; ok {
+		f.mu.Lock()
+		f.maxSize = maxSize
+		f.mu.Unlock()
+		return f, nil
+	}
+
+	f := &rotatingFile{path: path, maxSize: maxSize}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	logFiles[path] = f
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return fmt.Errorf("opening log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("opening log file: %w", err)
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "log rotation failed: %v\n", err)
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate keeps writing to the current file if the new one cannot be opened
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		if openErr := f.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	return f.open()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// newLogger builds the application logger from the log_level and log_format settings.
+// With a file the logs go there, and to stderr as well when alsoStderr is set.
+func newLogger(level, format, file string, maxSize int64, alsoStderr bool) (*slog.Logger, error) {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid log_level %q", level)
+		}
+	}
+
+	var out io.Writer = os.Stderr
+	if file != "" {
+		f, err := openLogFile(file, maxSize)
+		if err != nil {
+			return nil, err
+		}
+		out = f
+		if alsoStderr {
+			out = io.MultiWriter(os.Stderr, f)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case "", "json":
+		return slog.New(pollIDHandler{slog.NewJSONHandler(out, opts)}), nil
+	case "text":
+		return slog.New(pollIDHandler{slog.NewTextHandler(out, opts)}), nil
+	default:
+		return nil, fmt.Errorf("invalid log_format %q, expected json or text", format)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// matrixTxnCounter keeps transaction IDs unique within a process
+var matrixTxnCounter atomic.Int64
+
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+func matrixConfigured() bool {
+	return currentConfig().MatrixHomeserverURL != ""
+}
+
+func sendMatrixNotification(ctx context.Context, n Notification) error {
+	message := n.Message()
+	formatted := fmt.Sprintf("<strong>%s</strong><br>%s", html.EscapeString(n.Title()), html.EscapeString(message))
+	return postMatrixMessage(ctx, message, formatted)
+}
+
+// postMatrixMessage sends text to matrix_room_id. formatted is an HTML version of text,
+// used instead of it when matrix_html is set.
+func postMatrixMessage(ctx context.Context, text, formatted string) (err error) {
+	cfg := currentConfig()
+	message := matrixMessage{MsgType: "m.text", Body: text}
+	if cfg.MatrixHTML && formatted != "" {
+		message.Format = "org.matrix.custom.html"
+		message.FormattedBody = formatted
+	}
+	body, _ := json.Marshal(message)
+
+	defer func() { recordPollDelivery(pollIDFrom(ctx), "matrix", cfg.MatrixRoomID, string(body), err) }()
+	if dryRun(ctx, "matrix", cfg.MatrixRoomID, string(body)) {
+		return nil
+	}
+
+	// Sending is a PUT with a client chosen transaction ID so retries are deduplicated
+	txnID := fmt.Sprintf("ytn-%d-%d", time.Now().UnixNano(), matrixTxnCounter.Add(1))
+	endpoint := strings.TrimSuffix(cfg.MatrixHomeserverURL, "/") + "/_matrix/client/v3/rooms/" +
+		url.PathEscape(cfg.MatrixRoomID) + "/send/m.room.message/" + txnID
+
+	req, err := http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cfg.MatrixAccessToken)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error sending Matrix notification", "event", "notify", "platform", "matrix", "error", err)
+		return withKind(ErrTransient, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		var matrixErr struct {
+			ErrCode string `json:"errcode"`
+			Error   string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&matrixErr)
+		slog.ErrorContext(ctx, "Unexpected status code from Matrix", "event", "notify", "platform", "matrix", "status", resp.StatusCode, "errcode", matrixErr.ErrCode, "error", matrixErr.Error)
+		return &statusError{StatusCode: resp.StatusCode, Detail: matrixErr.ErrCode}
+	}
+	return nil
+}
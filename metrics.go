@@ -0,0 +1,65 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const defaultMetricsPath = "/metrics"
+
+var (
+	subscriberCountGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "youtube_subscriber_count",
+		Help: "Subscriber count of the channel at the last successful poll.",
+	}, []string{"channel_id"})
+	viewCountGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "youtube_view_count",
+		Help: "View count of the channel at the last successful poll.",
+	}, []string{"channel_id"})
+	videoCountGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "youtube_video_count",
+		Help: "Video count of the channel at the last successful poll.",
+	}, []string{"channel_id"})
+	apiErrorsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "youtube_api_errors_total",
+		Help: "Number of failed YouTube Data API calls.",
+	}, []string{"method"})
+	apiLatencyHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "youtube_api_call_duration_seconds",
+		Help:    "Latency of YouTube Data API calls.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+	notificationsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notifications_total",
+		Help: "Number of notification deliveries by platform and result (sent, failed or dry_run).",
+	}, []string{"platform", "result"})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "notification_queue_depth",
+		Help:        "Number of notifications waiting in an outbound queue.",
+		ConstLabels: prometheus.Labels{"queue": "telegram"},
+	}, func() float64 { return float64(len(telegramQueue)) })
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "notification_queue_depth",
+		Help:        "Number of notifications waiting in an outbound queue.",
+		ConstLabels: prometheus.Labels{"queue": "webhook_retry"},
+	}, func() float64 { return float64(queuedWebhookCount()) })
+)
+
+// observeAPICall records the latency and outcome of a YouTube API call started at start
+func observeAPICall(method string, start time.Time, err error) {
+	apiLatencyHistogram.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	recordQuotaUsage(method)
+	if err != nil {
+		apiErrorsCounter.WithLabelValues(method).Inc()
+	}
+}
+
+func recordChannelMetrics(stats ChannelStats) {
+	if !stats.HiddenSubscriberCount {
+		subscriberCountGauge.WithLabelValues(stats.ChannelID).Set(float64(stats.SubscriberCount))
+	}
+	viewCountGauge.WithLabelValues(stats.ChannelID).Set(float64(stats.ViewCount))
+	videoCountGauge.WithLabelValues(stats.ChannelID).Set(float64(stats.VideoCount))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+)
+
+var (
+	announcedMilestones      map[string][]uint64
+	announcedMilestonesMutex sync.Mutex
+)
+
+// checkMilestones announces configured thresholds crossed upward between previous and current.
+// When several milestones are passed in one poll only the highest one is announced, the others
+// are recorded as announced so they never fire later. Without a known previous count the crossed
+// milestones are recorded silently.
+func checkMilestones(ctx context.Context, channel ChannelConfig, previous, current uint64, known bool) {
+	if len(currentConfig().Milestones) == 0 {
+		return
+	}
+
+	announcedMilestonesMutex.Lock()
+	defer announcedMilestonesMutex.Unlock()
+
+	if announcedMilestones == nil {
+		announcedMilestones = loadAnnouncedMilestones()
+	}
+
+	announced := make(map[uint64]bool)
+	for _, threshold := range announcedMilestones[channel.ChannelID] {
+		announced[threshold] = true
+	}
+
+	var crossed []uint64
+	for _, threshold := range currentConfig().Milestones {
+		if threshold <= current && !announced[threshold] && (!known || previous < threshold) {
+			crossed = append(crossed, threshold)
+		}
+	}
+	if len(crossed) == 0 {
+		return
+	}
+	sort.Slice(crossed, func(i, j int) bool { return crossed[i] < crossed[j] })
+
+	announcedMilestones[channel.ChannelID] = append(announcedMilestones[channel.ChannelID], crossed...)
+	saveAnnouncedMilestones(announcedMilestones)
+
+	if !known {
+		slog.InfoContext(ctx, "Recording milestones already reached", "event", "milestone", "channel_id", channel.ChannelID, "milestones", crossed)
+		return
+	}
+	sendMilestoneNotification(ctx, channel, crossed[len(crossed)-1], current)
+}
+
+func sendMilestoneNotification(ctx context.Context, channel ChannelConfig, threshold, count uint64) {
+	slog.InfoContext(ctx, "Milestone reached", "event", "milestone", "channel_id", channel.ChannelID, "milestone", threshold, "subscriber_count", count)
+
+	text := fmt.Sprintf("🎉 %s just passed %s subscribers! Now at %s.", channel.Name(), formatCount(threshold), formatCount(count))
+	sendChannelMessage(ctx, channelMessage{ChannelID: channel.ChannelID, Type: "milestone", Title: "🎉 Milestone reached", Text: text})
+}
+
+func loadAnnouncedMilestones() map[string][]uint64 {
+	milestones := make(map[string][]uint64)
+	data, err := os.ReadFile("milestones.json")
+	if err != nil {
+		return milestones
+	}
+	if err := json.Unmarshal(data, &milestones); err != nil {
+		slog.Error("Error decoding milestones.json", "error", err)
+	}
+	return milestones
+}
+
+func saveAnnouncedMilestones(milestones map[string][]uint64) {
+	data, _ := json.Marshal(milestones)
+	if err := writeFileAtomic("milestones.json", data, 0644); err != nil {
+		slog.Error("Error saving milestones.json", "error", err)
+	}
+}
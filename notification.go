@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"math"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const defaultMessageTemplate = "{{.ChannelTitle}} now has {{humanize .Count}} {{.Metric}} ({{.SignedDelta}})"
+
+// templateFuncs are available in message_template
+var templateFuncs = template.FuncMap{
+	"humanize": formatCount,
+}
+
+// Metrics of the channel statistics that can be watched
+const (
+	metricSubscribers = "subscribers"
+	metricViews       = "views"
+	metricVideos      = "videos"
+	metricComments    = "comments"
+)
+
+// metricTitles are the headings used for notifications about each metric
+var metricTitles = map[string]string{
+	metricSubscribers: "Subscriber count update",
+	metricViews:       "View count update",
+	metricVideos:      "Video count update",
+	metricComments:    "Comment count update",
+}
+
+// Notification describes a change of one metric of a single channel
+type Notification struct {
+	ChannelID     string
+	ChannelTitle  string
+	Metric        string
+	Count         uint64
+	PreviousCount uint64
+	Delta         int64
+	// Windows holds the subscriber change over each of delta_windows, empty without history
+	Windows []WindowDelta
+	// PollID is the correlation ID of the poll cycle that noticed the change
+	PollID string
+}
+
+func newNotification(channel ChannelConfig, previous, count uint64) Notification {
+	return newMetricNotification(channel, metricSubscribers, previous, count)
+}
+
+func newMetricNotification(channel ChannelConfig, metric string, previous, count uint64) Notification {
+	return Notification{
+		ChannelID:     channel.ChannelID,
+		ChannelTitle:  channel.Name(),
+		Metric:        metric,
+		Count:         count,
+		PreviousCount: previous,
+		Delta:         countDelta(previous, count),
+	}
+}
+
+// Title returns a heading naming the metric that changed
+func (n Notification) Title() string {
+	return metricTitles[n.Metric]
+}
+
+// countDelta returns current - previous, saturating instead of overflowing int64
+func countDelta(previous, current uint64) int64 {
+	if current >= previous {
+		if d := current - previous; d <= math.MaxInt64 {
+			return int64(d)
+		}
+		return math.MaxInt64
+	}
+	if d := previous - current; d <= math.MaxInt64 {
+		return -int64(d)
+	}
+	return math.MinInt64
+}
+
+// SignedDelta formats the delta with an explicit sign, e.g. "+3" or "-1,024"
+func (n Notification) SignedDelta() string {
+	return formatDelta(n.Delta)
+}
+
+// Message renders the notification with the configured message template
+func (n Notification) Message() string {
+	var b strings.Builder
+	if err := currentConfig().messageTemplate.Execute(&b, n); err != nil {
+		// The template was checked at startup, fall back to the default wording anyway
+		return fmt.Sprintf("%s now has %s %s", n.ChannelTitle, formatCount(n.Count), n.Metric)
+	}
+	return b.String()
+}
+
+// parseMessageTemplate parses text, or the default template when empty, and renders it
+// once with sample data so field typos are reported at startup
+func parseMessageTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultMessageTemplate
+	}
+	tmpl, err := template.New("message").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	sample := Notification{ChannelID: "UC0000000000000000000000", ChannelTitle: "Sample", Metric: metricSubscribers, Count: 1000, PreviousCount: 990, Delta: 10,
+		Windows: []WindowDelta{{Window: "24h", Delta: 12, Available: true}, {Window: "7d"}}}
+	if err := tmpl.Execute(&strings.Builder{}, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// dryRun logs what would be sent and reports true when dry_run is enabled
+func dryRun(ctx context.Context, platform, target string, payload any) bool {
+	if !currentConfig().DryRun {
+		return false
+	}
+	slog.InfoContext(ctx, "Dry run, not sending notification", "event", "notify", "platform", platform, "target", target, "payload", payload)
+	return true
+}
+
+// dispatchNotification sends a subscriber count change to every configured platform, or
+// queues it for the next digest when digest_interval is set
+func dispatchNotification(ctx context.Context, n Notification) {
+	n.PollID = pollIDFrom(ctx)
+	if digestEnabled() {
+		addToDigest(n)
+		return
+	}
+	if platformReady("webhook", n.ChannelID) {
+		sendWebhookNotification(ctx, n)
+	}
+	if platformReady("telegram", n.ChannelID) {
+		sendTelegramNotification(ctx, n)
+	}
+	if currentConfig().DiscordWebhookURL != "" && platformReady("discord", n.ChannelID) {
+		sendDiscordNotification(ctx, n)
+	}
+	if slackWebhookFor(n.ChannelID) != "" && platformReady("slack", n.ChannelID) {
+		sendSlackNotification(ctx, n)
+	}
+	if matrixConfigured() && platformReady("matrix", n.ChannelID) {
+		sendMatrixNotification(ctx, n)
+	}
+	if pushoverConfigured() && platformReady("pushover", n.ChannelID) {
+		sendPushoverNotification(ctx, n)
+	}
+	if emailConfigured() && platformReady("email", n.ChannelID) {
+		sendEmailNotification(ctx, n)
+	}
+}
+
+// channelMessage is a notification other than a count change, such as a new video
+type channelMessage struct {
+	// ChannelID selects the per-channel routes, empty for messages using the global settings
+	ChannelID string
+	// Type tells webhook receivers what happened, e.g. new_video
+	Type  string
+	Title string
+	Text  string
+	// Markdown is the Telegram MarkdownV2 version of Text, the escaped Text when empty
+	Markdown string
+	// URL and Image are linked from the Discord embed
+	URL   string
+	Image string
+}
+
+// sendChannelMessage sends m to every configured platform, following the per-channel routes
+// of dispatchNotification
+func sendChannelMessage(ctx context.Context, m channelMessage) {
+	sendWebhookMessage(ctx, m)
+
+	markdown := m.Markdown
+	if markdown == "" {
+		markdown = escapeMarkdownV2(m.Text)
+	}
+	sendTelegramMessageTo(ctx, chatsFor(m.ChannelID), markdown, "MarkdownV2")
+
+	if currentConfig().DiscordWebhookURL != "" {
+		embed := discordEmbed{
+			Title:       m.Title,
+			Description: m.Text,
+			URL:         m.URL,
+			Color:       discordEmbedColor,
+			Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		}
+		if m.Image != "" {
+			embed.Image = &discordEmbedImage{URL: m.Image}
+		}
+		postDiscordEmbed(ctx, embed)
+	}
+	if webhookURL := slackWebhookFor(m.ChannelID); webhookURL != "" {
+		postSlackMessageTo(ctx, webhookURL, slackPayload{
+			Text: m.Text,
+			Blocks: []slackBlock{
+				{Type: "header", Text: &slackText{Type: "plain_text", Text: m.Title}},
+				{Type: "section", Text: &slackText{Type: "plain_text", Text: m.Text}},
+			},
+		})
+	}
+	if matrixConfigured() {
+		postMatrixMessage(ctx, m.Title+"\n"+m.Text, "<strong>"+html.EscapeString(m.Title)+"</strong><br>"+strings.ReplaceAll(html.EscapeString(m.Text), "\n", "<br>"))
+	}
+	if pushoverConfigured() {
+		postPushoverMessage(ctx, m.Title, m.Text)
+	}
+	if emailConfigured() {
+		sendEmail(ctx, m.Title, m.Text)
+	}
+}
+
+// sendStartNotification confirms that monitoring works, listing the counts of the first poll
+func sendStartNotification(ctx context.Context, stats []ChannelStats) {
+	names := make(map[string]string)
+	for _, channel := range currentConfig().Channels {
+		names[channel.ChannelID] = channel.Name()
+	}
+
+	lines := make([]string, 0, len(stats))
+	for _, s := range stats {
+		if s.HiddenSubscriberCount {
+			lines = append(lines, fmt.Sprintf("Monitoring started for %s (subscriber count hidden)", names[s.ChannelID]))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("Monitoring started for %s at %s subscribers", names[s.ChannelID], formatCount(s.SubscriberCount)))
+	}
+	slog.InfoContext(ctx, "Sending startup notification", "event", "notify", "channels", len(stats))
+	broadcastMessage(ctx, "monitoring_started", "Monitoring started", strings.Join(lines, "\n"))
+}
+
+// broadcastMessage sends a plain text message that concerns no single channel to every
+// configured platform, msgType is the type field of the webhook payload
+func broadcastMessage(ctx context.Context, msgType, title, text string) {
+	sendChannelMessage(ctx, channelMessage{Type: msgType, Title: title, Text: text})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+// webhookMessages returns the type and target host of every webhook delivery, oldest first
+func webhookMessages(t *testing.T) []string {
+	t.Helper()
+	deliveriesMutex.Lock()
+	recent := recentDeliveries()
+	deliveriesMutex.Unlock()
+
+	var messages []string
+	for i := len(recent) - 1; i >= 0; i-- {
+		if recent[i].Platform != "webhook" {
+			continue
+		}
+		var payload struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal([]byte(recent[i].Payload), &payload); err != nil {
+			t.Fatalf("decoding webhook payload %q: %v", recent[i].Payload, err)
+		}
+		messages = append(messages, payload.Type+" "+recent[i].Target)
+	}
+	return messages
+}
+
+func TestChannelMessagesFollowChannelRoutes(t *testing.T) {
+	resetPollState(t)
+	cfg := useConfig(t, `
+api_key: test
+webhook_url: https://global.example.com/hook
+dry_run: true
+channels:
+  - channel_id: UCaaaaaaaaaaaaaaaaaaaaaa
+    webhook_url: https://first.example.com/hook
+  - channel_id: UCbbbbbbbbbbbbbbbbbbbbbb
+`)
+
+	sendMilestoneNotification(context.Background(), cfg.Channels[0], 1000, 1001)
+	sendTargetReached(context.Background(), cfg.Channels[1], 5000, 5001)
+	broadcastMessage(context.Background(), "digest", "Subscriber digest", "Nothing changed")
+
+	want := []string{
+		"milestone https://first.example.com",
+		"target_reached https://global.example.com",
+		"digest https://global.example.com",
+	}
+	if got := webhookMessages(t); !slices.Equal(got, want) {
+		t.Errorf("webhook deliveries %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+)
+
+// Kinds of notification failures, match them with errors.Is on the errors returned by the send
+// functions. Failures of none of these kinds, such as a 400 for a malformed message, are permanent.
+var (
+	// ErrRateLimited means the platform asked to slow down, sending later may succeed
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnauthorized means the configured token, key or password was rejected
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrTransient covers network errors and server side failures worth retrying
+	ErrTransient = errors.New("transient failure")
+)
+
+// statusError is an unexpected HTTP status code returned by a notification platform
+type statusError struct {
+	StatusCode int
+	Detail     string
+}
+
+func (e *statusError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("unexpected status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Detail)
+}
+
+func (e *statusError) Unwrap() error {
+	return statusKind(e.StatusCode)
+}
+
+// statusKind maps an HTTP status code to the kind of failure, nil for permanent ones
+func statusKind(code int) error {
+	switch {
+	case code == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return ErrUnauthorized
+	case code == http.StatusRequestTimeout || code >= 500:
+		return ErrTransient
+	}
+	return nil
+}
+
+// kindError tags err with one of the failure kinds while keeping its message
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string { return e.err.Error() }
+
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// withKind tags err with kind, a nil kind returns err unchanged
+func withKind(kind, err error) error {
+	if kind == nil || err == nil {
+		return err
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// smtpKind maps an SMTP reply to the kind of failure. Replies in the 4xx range are temporary
+// by definition and anything that is not a reply is a network error.
+func smtpKind(err error) error {
+	var reply *textproto.Error
+	if !errors.As(err, &reply) {
+		return ErrTransient
+	}
+	switch {
+	case reply.Code == 530 || reply.Code == 534 || reply.Code == 535:
+		return ErrUnauthorized
+	case reply.Code >= 400 && reply.Code < 500:
+		return ErrTransient
+	}
+	return nil
+}
+
+// retryableError reports whether sending again later may succeed
+func retryableError(err error) bool {
+	return errors.Is(err, ErrTransient) || errors.Is(err, ErrRateLimited)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// How long a /login redirect may take to come back to /oauth2callback
+const oauthStateTTL = 10 * time.Minute
+
+// pendingLogin is a /login waiting for its callback
+type pendingLogin struct {
+	expiry time.Time
+	// PKCE code verifier sent with the token exchange
+	verifier string
+}
+
+var (
+	oauthStates      = make(map[string]pendingLogin)
+	oauthStatesMutex sync.Mutex
+)
+
+// newOAuthState returns a random state value valid for a single callback,
+// together with the PKCE code verifier of that login
+func newOAuthState() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	state := base64.RawURLEncoding.EncodeToString(b)
+	verifier := oauth2.GenerateVerifier()
+
+	oauthStatesMutex.Lock()
+	defer oauthStatesMutex.Unlock()
+	now := time.Now()
+	for s, login := range oauthStates {
+		if now.After(login.expiry) {
+			delete(oauthStates, s)
+		}
+	}
+	oauthStates[state] = pendingLogin{expiry: now.Add(oauthStateTTL), verifier: verifier}
+	return state, verifier, nil
+}
+
+// consumeOAuthState returns the code verifier of state and whether it was issued and has
+// not expired, it can only be used once
+func consumeOAuthState(state string) (string, bool) {
+	oauthStatesMutex.Lock()
+	defer oauthStatesMutex.Unlock()
+	login, ok := oauthStates[state]
+	if !ok {
+		return "", false
+	}
+	delete(oauthStates, state)
+	return login.verifier, time.Now().Before(login.expiry)
+}
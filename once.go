@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+)
+
+// printStatsOnce fetches the statistics of every configured channel and writes them to w as a
+// JSON array, in the order of the configuration. It backs the -once flag.
+func printStatsOnce(ctx context.Context, w io.Writer) error {
+	fetcher, err := newFetcher(ctx)
+	if err != nil {
+		return err
+	}
+
+	channels := currentConfig().Channels
+	all := make([]ChannelStats, 0, len(channels))
+	for _, batch := range channelBatches(channels) {
+		stats, err := fetcher.FetchChannelStats(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for _, channel := range batch {
+			s, ok := stats[channel.ChannelID]
+			if !ok {
+				slog.Warn("No channel found", "channel_id", channel.ChannelID)
+				continue
+			}
+			all = append(all, s)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(all)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+// pollIDKey is the context key of the poll cycle correlation ID
+type pollIDKey struct{}
+
+// newPollContext returns ctx carrying a new correlation ID for the poll cycle starting now
+func newPollContext(ctx context.Context) context.Context {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return withPollID(ctx, hex.EncodeToString(b))
+}
+
+// withPollID returns ctx carrying id, so work finished after the cycle, such as a queued
+// Telegram message or a webhook retry, keeps the ID of the cycle that caused it
+func withPollID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, pollIDKey{}, id)
+}
+
+// pollIDFrom returns the poll cycle ID carried by ctx, empty outside of a poll cycle
+func pollIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(pollIDKey{}).(string)
+	return id
+}
+
+// pollIDHandler adds the poll_id attribute to records logged with the context of a poll cycle,
+// unless they already carry one
+type pollIDHandler struct {
+	slog.Handler
+}
+
+func (h pollIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := pollIDFrom(ctx); id != "" && !hasPollID(r) {
+		r.AddAttrs(slog.String("poll_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func hasPollID(r slog.Record) bool {
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		found = a.Key == "poll_id"
+		return !found
+	})
+	return found
+}
+
+func (h pollIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return pollIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h pollIDHandler) WithGroup(name string) slog.Handler {
+	return pollIDHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const pushoverMessagesURL = "https://api.pushover.net/1/messages.json"
+
+func pushoverConfigured() bool {
+	return currentConfig().PushoverToken != ""
+}
+
+func sendPushoverNotification(ctx context.Context, n Notification) error {
+	return postPushoverMessage(ctx, n.Title(), n.Message())
+}
+
+// postPushoverMessage sends a message to pushover_user. Emergency messages, priority 2, are
+// repeated every minute for an hour until acknowledged.
+func postPushoverMessage(ctx context.Context, title, message string) (err error) {
+	cfg := currentConfig()
+	form := url.Values{
+		"token":    {cfg.PushoverToken},
+		"user":     {cfg.PushoverUser},
+		"title":    {title},
+		"message":  {message},
+		"priority": {strconv.Itoa(cfg.PushoverPriority)},
+	}
+	if cfg.PushoverSound != "" {
+		form.Set("sound", cfg.PushoverSound)
+	}
+	if cfg.PushoverPriority == 2 {
+		form.Set("retry", "60")
+		form.Set("expire", "3600")
+	}
+
+	defer func() { recordPollDelivery(pollIDFrom(ctx), "pushover", pushoverMessagesURL, message, err) }()
+	if dryRun(ctx, "pushover", pushoverMessagesURL, map[string]string{"title": title, "message": message}) {
+		return nil
+	}
+
+	resp, err := httpClient.Post(pushoverMessagesURL, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error sending Pushover notification", "event", "notify", "platform", "pushover", "error", err)
+		return withKind(ErrTransient, err)
+	}
+	defer resp.Body.Close()
+
+	// The application quota is monthly, retrying before the reset is pointless
+	if resp.StatusCode == http.StatusTooManyRequests {
+		reset := resp.Header.Get("X-Limit-App-Reset")
+		if unix, err := strconv.ParseInt(reset, 10, 64); err == nil {
+			reset = time.Unix(unix, 0).UTC().Format(time.RFC3339)
+		}
+		slog.ErrorContext(ctx, "Pushover message limit reached", "event", "notify", "platform", "pushover",
+			"limit", resp.Header.Get("X-Limit-App-Limit"), "remaining", resp.Header.Get("X-Limit-App-Remaining"), "reset", reset)
+		return withKind(ErrRateLimited, fmt.Errorf("pushover message limit reached until %s", reset))
+	}
+
+	if resp.StatusCode/100 != 2 {
+		var body struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		slog.ErrorContext(ctx, "Unexpected status code from Pushover", "event", "notify", "platform", "pushover", "status", resp.StatusCode, "errors", body.Errors)
+		return &statusError{StatusCode: resp.StatusCode, Detail: strings.Join(body.Errors, ", ")}
+	}
+	return nil
+}
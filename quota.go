@@ -0,0 +1,93 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// The default daily quota of a YouTube Data API project
+const defaultDailyQuota = 10000
+
+// apiCallCosts are the quota units charged per call of each method
+var apiCallCosts = map[string]int64{
+	"channels.list":      1,
+	"playlistItems.list": 1,
+	"search.list":        100,
+	"videos.list":        1,
+}
+
+var (
+	quotaUsed  int64
+	quotaDay   string
+	quotaMutex sync.Mutex
+)
+
+// quotaLocation is where the daily quota resets at midnight
+var quotaLocation = func() *time.Location {
+	if loc, err := time.LoadLocation("America/Los_Angeles"); err == nil {
+		return loc
+	}
+	return time.FixedZone("PST", -8*60*60)
+}()
+
+func dailyQuota() int64 {
+	if q := currentConfig().DailyQuota; q > 0 {
+		return q
+	}
+	return defaultDailyQuota
+}
+
+// recordQuotaUsage adds the cost of one call of method to today's estimate
+func recordQuotaUsage(method string) {
+	quotaMutex.Lock()
+	defer quotaMutex.Unlock()
+	if day := time.Now().In(quotaLocation).Format(time.DateOnly); day != quotaDay {
+		quotaDay = day
+		quotaUsed = 0
+	}
+	quotaUsed += apiCallCosts[method]
+}
+
+// quotaUsage returns the estimated units consumed since the last quota reset
+func quotaUsage() int64 {
+	quotaMutex.Lock()
+	defer quotaMutex.Unlock()
+	if time.Now().In(quotaLocation).Format(time.DateOnly) != quotaDay {
+		return 0
+	}
+	return quotaUsed
+}
+
+// estimatePollCost returns the quota units one poll of every channel consumes
+func estimatePollCost(cfg *Config) int64 {
+	channels := int64(len(cfg.Channels))
+	batches := (channels + maxChannelsPerRequest - 1) / maxChannelsPerRequest
+	// One Channels.List per batch, with notify_uploads one PlaylistItems.List per channel more
+	cost := batches * apiCallCosts["channels.list"]
+	if cfg.NotifyUploads {
+		cost += channels * apiCallCosts["playlistItems.list"]
+	}
+	if cfg.WatchLivestreams {
+		cost += channels * apiCallCosts["search.list"]
+	}
+	return cost
+}
+
+// minQuotaInterval returns the shortest poll interval that stays within the daily quota
+func minQuotaInterval(cfg *Config) time.Duration {
+	quota := cfg.DailyQuota
+	if quota <= 0 {
+		quota = defaultDailyQuota
+	}
+	return time.Duration(estimatePollCost(cfg)) * 24 * time.Hour / time.Duration(quota)
+}
+
+// checkQuotaBudget warns when the poll interval would exhaust the daily quota
+func checkQuotaBudget(cfg *Config) {
+	if minInterval := minQuotaInterval(cfg); cfg.pollInterval < minInterval {
+		slog.Warn("Poll interval exceeds the daily API quota", "event", "quota",
+			"poll_interval", cfg.pollInterval.String(), "min_interval", minInterval.String(),
+			"units_per_poll", estimatePollCost(cfg))
+	}
+}
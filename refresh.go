@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// refreshResult is the outcome of a poll triggered through /refresh
+type refreshResult struct {
+	stats []ChannelStats
+	err   error
+}
+
+var (
+	// refreshSignal wakes the monitor loop, its buffer of one coalesces concurrent requests
+	refreshSignal  = make(chan struct{}, 1)
+	refreshWaiters []chan refreshResult
+	refreshMutex   sync.Mutex
+)
+
+// requestRefresh asks the monitor loop for an immediate poll. Every request made before the
+// poll starts receives the result of that same poll.
+func requestRefresh() <-chan refreshResult {
+	result := make(chan refreshResult, 1)
+	refreshMutex.Lock()
+	refreshWaiters = append(refreshWaiters, result)
+	refreshMutex.Unlock()
+
+	select {
+	case refreshSignal <- struct{}{}:
+	default: // A poll is already pending
+	}
+	return result
+}
+
+func takeRefreshWaiters() []chan refreshResult {
+	refreshMutex.Lock()
+	defer refreshMutex.Unlock()
+	waiters := refreshWaiters
+	refreshWaiters = nil
+	return waiters
+}
+
+func completeRefreshes(waiters []chan refreshResult, result refreshResult) {
+	for _, waiter := range waiters {
+		waiter <- result
+	}
+}
+
+// failRefreshes answers every pending request when the monitor loop stops
+func failRefreshes(err error) {
+	completeRefreshes(takeRefreshWaiters(), refreshResult{err: err})
+}
+
+// handleRefresh triggers an immediate poll and responds with the statistics it fetched
+func handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !monitorRunning.Load() {
+		http.Error(w, "Monitor is not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	var result refreshResult
+	select {
+	case result = <-requestRefresh():
+	case <-r.Context().Done():
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if result.err != nil {
+		slog.Warn("Refresh failed", "event", "poll", "error", result.err)
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"error": result.err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string][]ChannelStats{"channels": result.stats})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchReload re-reads the config file at path on SIGHUP until ctx is done.
+// An invalid file is rejected and the running configuration stays in place.
+func watchReload(ctx context.Context, path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+
+		slog.Info("Reloading configuration", "event", "reload", "path", path)
+		next, err := loadConfig(path)
+		if err != nil {
+			slog.Error("Configuration reload rejected, keeping the running configuration", "event", "reload", "error", err)
+			continue
+		}
+
+		prev := currentConfig()
+		if ignored := keepStaticSettings(prev, next); len(ignored) > 0 {
+			slog.Warn("Some settings require a restart and were ignored", "event", "reload", "settings", ignored)
+		}
+		setConfig(next)
+		// Titles in the old language are fetched again with the next poll
+		if next.Language != prev.Language {
+			forgetTitles()
+		}
+		slog.SetDefault(next.logger)
+		checkQuotaBudget(next)
+		slog.Info("Configuration reloaded", "event", "reload")
+	}
+}
+
+// keepStaticSettings copies the settings that are only read at startup from prev into next
+// and returns the names of those that differ
+func keepStaticSettings(prev, next *Config) []string {
+	var ignored []string
+	if next.ListenAddr != prev.ListenAddr {
+		ignored = append(ignored, "listen_addr")
+		next.ListenAddr = prev.ListenAddr
+	}
+	if next.TLSCertFile != prev.TLSCertFile || next.TLSKeyFile != prev.TLSKeyFile || next.TLSRedirectAddr != prev.TLSRedirectAddr {
+		ignored = append(ignored, "tls_cert_file/tls_key_file/tls_redirect_addr")
+		next.TLSCertFile, next.TLSKeyFile, next.TLSRedirectAddr = prev.TLSCertFile, prev.TLSKeyFile, prev.TLSRedirectAddr
+	}
+	if next.ClientID != prev.ClientID || next.ClientSecret != prev.ClientSecret || next.RedirectURL != prev.RedirectURL {
+		ignored = append(ignored, "client_id/client_secret/redirect_url")
+		next.ClientID, next.ClientSecret, next.RedirectURL = prev.ClientID, prev.ClientSecret, prev.RedirectURL
+	}
+	if next.ServiceAccountFile != prev.ServiceAccountFile || next.APIKey != prev.APIKey {
+		ignored = append(ignored, "service_account_file/api_key")
+		next.ServiceAccountFile, next.APIKey = prev.ServiceAccountFile, prev.APIKey
+	}
+	if next.DBPath != prev.DBPath {
+		ignored = append(ignored, "db_path")
+		next.DBPath = prev.DBPath
+	}
+	if next.MetricsEnabled != prev.MetricsEnabled || next.MetricsPath != prev.MetricsPath {
+		ignored = append(ignored, "metrics_enabled/metrics_path")
+		next.MetricsEnabled, next.MetricsPath = prev.MetricsEnabled, prev.MetricsPath
+	}
+	if next.httpTimeout != prev.httpTimeout {
+		ignored = append(ignored, "http_timeout")
+		next.HTTPTimeout, next.httpTimeout = prev.HTTPTimeout, prev.httpTimeout
+	}
+	if next.ProxyURL != prev.ProxyURL {
+		ignored = append(ignored, "proxy_url")
+		next.ProxyURL, next.proxyURL = prev.ProxyURL, prev.proxyURL
+	}
+	if next.WebhookCAFile != prev.WebhookCAFile {
+		ignored = append(ignored, "webhook_ca_file")
+		next.WebhookCAFile, next.webhookRootCAs = prev.WebhookCAFile, prev.webhookRootCAs
+	}
+	return ignored
+}
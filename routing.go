@@ -0,0 +1,38 @@
+package main
+
+// Notifications about a channel go to the chat_ids, webhook_url and slack_webhook_url of its
+// entry under channels. Each one that is unset falls back to the global setting of the same
+// name, for webhooks that is every entry of webhook_url and webhooks. New videos, milestones and
+// targets are routed the same way. The other platforms always use the global settings, drop
+// alerts go to alert_chat_ids and alert_webhook_url.
+
+// channelByID returns the configuration of a monitored channel
+func channelByID(channelID string) (ChannelConfig, bool) {
+	for _, channel := range currentConfig().Channels {
+		if channel.ChannelID == channelID {
+			return channel, true
+		}
+	}
+	return ChannelConfig{}, false
+}
+
+func chatsFor(channelID string) []ChatConfig {
+	if channel, ok := channelByID(channelID); ok && len(channel.ChatIDs) > 0 {
+		return channel.ChatIDs
+	}
+	return currentConfig().ChatIDs
+}
+
+func webhooksFor(channelID string) []WebhookConfig {
+	if channel, ok := channelByID(channelID); ok && channel.WebhookURL != "" {
+		return []WebhookConfig{{URL: channel.WebhookURL}}
+	}
+	return currentConfig().Webhooks
+}
+
+func slackWebhookFor(channelID string) string {
+	if channel, ok := channelByID(channelID); ok && channel.SlackWebhookURL != "" {
+		return channel.SlackWebhookURL
+	}
+	return currentConfig().SlackWebhookURL
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type   string      `json:"type"`
+	Text   *slackText  `json:"text,omitempty"`
+	Fields []slackText `json:"fields,omitempty"`
+}
+
+type slackPayload struct {
+	// Text is shown in push notifications and clients without block support
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+func sendSlackNotification(ctx context.Context, n Notification) error {
+	message := n.Message()
+	return postSlackMessageTo(ctx, slackWebhookFor(n.ChannelID), slackPayload{
+		Text: message,
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: n.Title()}},
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: message}},
+			{Type: "section", Fields: []slackText{
+				{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n%s", n.Metric, formatCount(n.Count))},
+				{Type: "mrkdwn", Text: fmt.Sprintf("*Change*\n%s", n.SignedDelta())},
+			}},
+		},
+	})
+}
+
+func postSlackMessageTo(ctx context.Context, webhookURL string, payload slackPayload) (err error) {
+	limit := messageLimit("slack")
+	payload.Text = truncateMessage(payload.Text, limit)
+	for _, block := range payload.Blocks {
+		if block.Type == "section" && block.Text != nil {
+			block.Text.Text = truncateMessage(block.Text.Text, limit)
+		}
+	}
+	body, _ := json.Marshal(payload)
+	defer func() { recordPollDelivery(pollIDFrom(ctx), "slack", webhookURL, string(body), err) }()
+	if dryRun(ctx, "slack", webhookURL, string(body)) {
+		return nil
+	}
+
+	resp, err := httpClient.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error sending Slack notification", "event", "notify", "platform", "slack", "error", err)
+		return withKind(ErrTransient, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		slog.ErrorContext(ctx, "Unexpected status code from Slack", "event", "notify", "platform", "slack", "status", resp.StatusCode, "body", string(respBody))
+		return &statusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
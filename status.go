@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// monitorRunning is true while monitorSubscriberCount is looping
+var monitorRunning atomic.Bool
+
+type statusResponse struct {
+	MonitorRunning   bool             `json:"monitor_running"`
+	LastPoll         *time.Time       `json:"last_poll,omitempty"`
+	TokenExpiry      *time.Time       `json:"token_expiry,omitempty"`
+	SubscriberCounts map[string]int64 `json:"subscriber_counts"`
+	HiddenCounts     []string         `json:"hidden_subscriber_counts,omitempty"`
+	QuotaUsed        int64            `json:"quota_used"`
+	QuotaLimit       int64            `json:"quota_limit"`
+}
+
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	resp := statusResponse{
+		MonitorRunning: monitorRunning.Load(),
+		QuotaUsed:      quotaUsage(),
+		QuotaLimit:     dailyQuota(),
+	}
+
+	tokenMutex.Lock()
+	if token != nil && !token.Expiry.IsZero() {
+		expiry := token.Expiry
+		resp.TokenExpiry = &expiry
+	}
+	tokenMutex.Unlock()
+
+	lastPollMutex.Lock()
+	if !lastPollTime.IsZero() {
+		t := lastPollTime
+		resp.LastPoll = &t
+	}
+	lastPollMutex.Unlock()
+
+	latestCountMutex.Lock()
+	resp.SubscriberCounts = make(map[string]int64, len(latestCount))
+	for id, count := range latestCount {
+		resp.SubscriberCounts[id] = count
+	}
+	for id := range hiddenSubscribers {
+		resp.HiddenCounts = append(resp.HiddenCounts, id)
+	}
+	latestCountMutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// requireBearerToken rejects requests without the configured bearer_token, it is a no-op when unset
+func requireBearerToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Valid Basic Auth credentials are accepted too, a request carries only one Authorization header
+		if currentConfig().BearerToken != "" && !bearerTokenValid(r) && !basicAuthValid(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireCredentials protects endpoints that act on behalf of the operator. Unlike
+// requireBearerToken it refuses every request while neither bearer_token nor http_username
+// is configured.
+func requireCredentials(next http.HandlerFunc) http.HandlerFunc {
+	protected := requireBearerToken(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg := currentConfig(); cfg.BearerToken == "" && cfg.HTTPUsername == "" {
+			http.Error(w, "Forbidden, configure bearer_token or http_username to use this endpoint", http.StatusForbidden)
+			return
+		}
+		protected(w, r)
+	}
+}
+
+func bearerTokenValid(r *http.Request) bool {
+	expected := currentConfig().BearerToken
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return expected != "" && ok && subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) == 1
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// targetState records on which side of its target a channel was last seen, persisted to
+// targets.json so crossing the target is announced once and not again after a restart
+type targetState struct {
+	Target uint64 `json:"target"`
+	Above  bool   `json:"above"`
+}
+
+var (
+	targetStates      map[string]targetState
+	targetStatesMutex sync.Mutex
+)
+
+// channelTarget returns the target of the channel, its own one or the global target
+func channelTarget(channel ChannelConfig) uint64 {
+	if channel.Target > 0 {
+		return channel.Target
+	}
+	return currentConfig().Target
+}
+
+// checkTarget announces the subscriber count reaching the target and, with target_notify_below,
+// falling back under it. The first count seen for a target is only recorded.
+func checkTarget(ctx context.Context, channel ChannelConfig, count uint64) {
+	target := channelTarget(channel)
+	if target == 0 {
+		return
+	}
+
+	targetStatesMutex.Lock()
+	defer targetStatesMutex.Unlock()
+
+	if targetStates == nil {
+		targetStates = loadTargetStates()
+	}
+
+	above := count >= target
+	state, ok := targetStates[channel.ChannelID]
+	if ok && state.Target == target && state.Above == above {
+		return
+	}
+	targetStates[channel.ChannelID] = targetState{Target: target, Above: above}
+	saveTargetStates(targetStates)
+
+	switch {
+	case !ok || state.Target != target:
+		slog.InfoContext(ctx, "Recording side of subscriber target", "event", "target", "channel_id", channel.ChannelID, "target", target, "above", above)
+	case above:
+		sendTargetReached(ctx, channel, target, count)
+	case currentConfig().TargetNotifyBelow:
+		sendTargetLost(ctx, channel, target, count)
+	}
+}
+
+func sendTargetReached(ctx context.Context, channel ChannelConfig, target, count uint64) {
+	slog.InfoContext(ctx, "Subscriber target reached", "event", "target", "channel_id", channel.ChannelID, "target", target, "subscriber_count", count)
+
+	text := fmt.Sprintf("🎯 %s reached the goal of %s subscribers! Now at %s.", channel.Name(), formatCount(target), formatCount(count))
+	sendChannelMessage(ctx, channelMessage{ChannelID: channel.ChannelID, Type: "target_reached", Title: "🎯 Target reached", Text: text})
+}
+
+func sendTargetLost(ctx context.Context, channel ChannelConfig, target, count uint64) {
+	slog.InfoContext(ctx, "Subscriber count fell below target", "event", "target", "channel_id", channel.ChannelID, "target", target, "subscriber_count", count)
+
+	text := fmt.Sprintf("%s dropped below the goal of %s subscribers, now at %s.", channel.Name(), formatCount(target), formatCount(count))
+	sendChannelMessage(ctx, channelMessage{ChannelID: channel.ChannelID, Type: "target_lost", Title: "Below target", Text: text})
+}
+
+func loadTargetStates() map[string]targetState {
+	states := make(map[string]targetState)
+	data, err := os.ReadFile("targets.json")
+	if err != nil {
+		return states
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		slog.Error("Error decoding targets.json", "error", err)
+	}
+	return states
+}
+
+func saveTargetStates(states map[string]targetState) {
+	data, _ := json.Marshal(states)
+	if err := writeFileAtomic("targets.json", data, 0644); err != nil {
+		slog.Error("Error saving targets.json", "error", err)
+	}
+}
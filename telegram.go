@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	telegramMaxAttempts  = 3
+	telegramInitialDelay = time.Second
+	// Telegram allows about 30 messages per second across all chats of a bot
+	defaultTelegramRateLimit = 30
+	// Messages waiting to be sent, further messages are dropped
+	telegramQueueSize = 256
+)
+
+// telegramJob is a message waiting in telegramQueue
+type telegramJob struct {
+	chats     []ChatConfig
+	text      string
+	parseMode string
+	// pollID is the cycle that queued the message, it is sent after the cycle ended
+	pollID string
+}
+
+var (
+	telegramQueue     = make(chan telegramJob, telegramQueueSize)
+	telegramQueueOnce sync.Once
+	// telegramPending counts queued and in-flight messages for the shutdown flush
+	telegramPending sync.WaitGroup
+	// Every bot has its own rate limit, the limiters are keyed by bot key
+	telegramLimiters      = make(map[string]*rate.Limiter)
+	telegramLimitersMutex sync.Mutex
+)
+
+// telegramLimiter returns the limiter of the bot, applying the current telegram_rate_limit
+func telegramLimiter(botKey string) *rate.Limiter {
+	telegramLimitersMutex.Lock()
+	defer telegramLimitersMutex.Unlock()
+	limiter, ok := telegramLimiters[botKey]
+	if !ok {
+		limiter = rate.NewLimiter(defaultTelegramRateLimit, 1)
+		telegramLimiters[botKey] = limiter
+	}
+	limiter.SetLimit(rate.Limit(currentConfig().TelegramRateLimit))
+	return limiter
+}
+
+// BotConfig is a Telegram bot and the chats it sends notifications to
+type BotConfig struct {
+	BotKey  string       `yaml:"bot_key"`
+	ChatIDs []ChatConfig `yaml:"chat_ids"`
+}
+
+// telegramError is a failed Telegram API call
+type telegramError struct {
+	StatusCode  int
+	Description string
+	RetryAfter  time.Duration
+}
+
+func (e *telegramError) Error() string {
+	return fmt.Sprintf("telegram returned %d: %s", e.StatusCode, e.Description)
+}
+
+func (e *telegramError) Unwrap() error {
+	return statusKind(e.StatusCode)
+}
+
+// ChatConfig is a Telegram chat receiving notifications. A plain string in the YAML is
+// accepted as the chat ID.
+type ChatConfig struct {
+	ChatID string `yaml:"chat_id"`
+	// MessageThreadID targets a topic of a forum group
+	MessageThreadID int64 `yaml:"message_thread_id"`
+	// Silent delivers messages without a sound, it defaults to true
+	Silent *bool `yaml:"silent"`
+	// botKey is the bot sending to the chat, set by loadConfig
+	botKey string
+}
+
+func (c *ChatConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		c.ChatID = value.Value
+		return nil
+	}
+	type plain ChatConfig
+	return value.Decode((*plain)(c))
+}
+
+func (c ChatConfig) silent() bool {
+	return c.Silent == nil || *c.Silent
+}
+
+func sendTelegramNotification(ctx context.Context, n Notification) {
+	sendTelegramMessageTo(ctx, chatsFor(n.ChannelID), escapeMarkdownV2(n.Message()), "MarkdownV2")
+}
+
+// Characters that must be escaped anywhere in a MarkdownV2 message
+const markdownV2Reserved = "_*[]()~`>#+-=|{}.!\\"
+
+// escapeMarkdownV2 escapes s so Telegram renders it literally with parse_mode MarkdownV2
+func escapeMarkdownV2(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if strings.ContainsRune(markdownV2Reserved, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// escapeMarkdownV2URL escapes s for use inside the (...) part of a MarkdownV2 inline link
+func escapeMarkdownV2URL(s string) string {
+	return strings.NewReplacer("\\", "\\\\", ")", "\\)").Replace(s)
+}
+
+// sendTelegramMessage sends text to every chat of every bot, parseMode may be empty for plain text
+func sendTelegramMessage(ctx context.Context, text string, parseMode string) {
+	sendTelegramMessageTo(ctx, currentConfig().ChatIDs, text, parseMode)
+}
+
+// sendTelegramMessageTo queues text for chats without waiting for it to be sent.
+// The queue is drained by a single worker paced by telegram_rate_limit.
+func sendTelegramMessageTo(ctx context.Context, chats []ChatConfig, text string, parseMode string) {
+	if len(chats) == 0 {
+		return
+	}
+	telegramQueueOnce.Do(func() { go runTelegramQueue() })
+
+	telegramPending.Add(1)
+	select {
+	case telegramQueue <- telegramJob{chats: chats, text: text, parseMode: parseMode, pollID: pollIDFrom(ctx)}:
+	default:
+		telegramPending.Done()
+		slog.ErrorContext(ctx, "Telegram queue is full, dropping message", "event", "notify", "platform", "telegram", "chats", len(chats))
+	}
+}
+
+func runTelegramQueue() {
+	for job := range telegramQueue {
+		deliverTelegramMessage(withPollID(context.Background(), job.pollID), job.chats, job.text, job.parseMode)
+		telegramPending.Done()
+	}
+}
+
+// deliverTelegramMessage sends text to chats, one rate limited message per chat.
+// Every chat is attempted even if sending to an earlier one failed.
+func deliverTelegramMessage(ctx context.Context, chats []ChatConfig, text string, parseMode string) error {
+	var errs []error
+	for _, chat := range chats {
+		if err := sendTelegramWithRetry(ctx, chat, text, parseMode); err != nil {
+			slog.ErrorContext(ctx, "Giving up on Telegram notification", "event", "notify", "platform", "telegram", "chat_id", chat.ChatID, "error", err)
+			errs = append(errs, fmt.Errorf("chat %s: %w", chat.ChatID, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		slog.ErrorContext(ctx, "Telegram notification failed for some chats", "event", "notify", "platform", "telegram",
+			"failed", len(errs), "total", len(chats), "error", errors.Join(errs...))
+	}
+	return errors.Join(errs...)
+}
+
+// sendTelegramWithRetry retries network errors, 5xx and 429 responses with exponential backoff.
+// A 429 waits for the retry_after duration requested by Telegram instead.
+func sendTelegramWithRetry(ctx context.Context, chat ChatConfig, text, parseMode string) (err error) {
+	if parseMode == "MarkdownV2" {
+		text = truncateMarkdownV2(text, messageLimit("telegram"))
+	} else {
+		text = truncateMessage(text, messageLimit("telegram"))
+	}
+	defer func() { recordPollDelivery(pollIDFrom(ctx), "telegram", chat.ChatID, text, err) }()
+	delay := telegramInitialDelay
+	for attempt := 1; ; attempt++ {
+		_ = telegramLimiter(chat.botKey).Wait(context.Background())
+		err := postTelegramMessage(ctx, chat, text, parseMode)
+		if err == nil {
+			return nil
+		}
+
+		if !retryableError(err) {
+			return err
+		}
+		wait := delay
+		if tgErr, ok := err.(*telegramError); ok && tgErr.RetryAfter > 0 {
+			wait = tgErr.RetryAfter
+		}
+		if attempt == telegramMaxAttempts {
+			return err
+		}
+
+		slog.WarnContext(ctx, "Telegram send failed, retrying", "event", "notify", "platform", "telegram", "chat_id", chat.ChatID, "attempt", attempt, "wait", wait.String(), "error", err)
+		time.Sleep(wait)
+		delay *= 2
+	}
+}
+
+func postTelegramMessage(ctx context.Context, chat ChatConfig, text, parseMode string) error {
+	disableNotification := strconv.FormatBool(chat.silent())
+	if dryRun(ctx, "telegram", "https://api.telegram.org/bot<redacted>/sendMessage", map[string]string{"chat_id": chat.ChatID, "text": text, "parse_mode": parseMode, "disable_notification": disableNotification, "message_thread_id": strconv.FormatInt(chat.MessageThreadID, 10)}) {
+		return nil
+	}
+
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", chat.botKey)
+	method := "POST"
+
+	payload := &bytes.Buffer{}
+	writer := multipart.NewWriter(payload)
+	_ = writer.WriteField("text", text)
+	_ = writer.WriteField("chat_id", chat.ChatID)
+	if chat.MessageThreadID != 0 {
+		_ = writer.WriteField("message_thread_id", strconv.FormatInt(chat.MessageThreadID, 10))
+	}
+	_ = writer.WriteField("caption", "")
+	if parseMode != "" {
+		_ = writer.WriteField("parse_mode", parseMode)
+	}
+	_ = writer.WriteField("disable_notification", disableNotification)
+	err := writer.Close()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, url, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return withKind(ErrTransient, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var body struct {
+		Description string `json:"description"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	_ = json.NewDecoder(res.Body).Decode(&body)
+	return &telegramError{
+		StatusCode:  res.StatusCode,
+		Description: body.Description,
+		RetryAfter:  time.Duration(body.Parameters.RetryAfter) * time.Second,
+	}
+}
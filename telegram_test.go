@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"_", `\_`},
+		{"*", `\*`},
+		{"[", `\[`},
+		{"]", `\]`},
+		{"(", `\(`},
+		{")", `\)`},
+		{"~", `\~`},
+		{"`", "\\`"},
+		{">", `\>`},
+		{"#", `\#`},
+		{"+", `\+`},
+		{"-", `\-`},
+		{"=", `\=`},
+		{"|", `\|`},
+		{"{", `\{`},
+		{"}", `\}`},
+		{".", `\.`},
+		{"!", `\!`},
+		{`\`, `\\`},
+		{"Channel now has 1,234 subscribers", "Channel now has 1,234 subscribers"},
+		{"Mr. Beast (+5)", `Mr\. Beast \(\+5\)`},
+		{"Ünïcødé 🎉 _x_", `Ünïcødé 🎉 \_x\_`},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := escapeMarkdownV2(tt.in); got != tt.want {
+			t.Errorf("escapeMarkdownV2(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// Every reserved character must have a case in TestEscapeMarkdownV2
+func TestEscapeMarkdownV2CoversReserved(t *testing.T) {
+	if n := utf8.RuneCountInString(markdownV2Reserved); n != 19 {
+		t.Fatalf("markdownV2Reserved has %d characters, the table test covers 19", n)
+	}
+	for _, r := range markdownV2Reserved {
+		if got, want := escapeMarkdownV2(string(r)), `\`+string(r); got != want {
+			t.Errorf("escapeMarkdownV2(%q) = %q, want %q", r, got, want)
+		}
+	}
+}
+
+func TestChatConfigUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name, in string
+		chatID   string
+		threadID int64
+		silent   bool
+	}{
+		{name: "plain string", in: `"-1001234567890"`, chatID: "-1001234567890", silent: true},
+		{name: "plain number", in: `123456789`, chatID: "123456789", silent: true},
+		{name: "mapping", in: "chat_id: \"-1001234567890\"\nmessage_thread_id: 42", chatID: "-1001234567890", threadID: 42, silent: true},
+		{name: "mapping without topic", in: "chat_id: \"@channel\"\nsilent: false", chatID: "@channel", silent: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var chat ChatConfig
+			if err := yaml.Unmarshal([]byte(tt.in), &chat); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if chat.ChatID != tt.chatID || chat.MessageThreadID != tt.threadID || chat.silent() != tt.silent {
+				t.Errorf("got chat_id %q, message_thread_id %d, silent %v, want %q, %d, %v",
+					chat.ChatID, chat.MessageThreadID, chat.silent(), tt.chatID, tt.threadID, tt.silent)
+			}
+		})
+	}
+}
+
+func TestChatConfigUnmarshalYAMLList(t *testing.T) {
+	var chats []ChatConfig
+	in := "- \"111\"\n- chat_id: \"222\"\n  message_thread_id: 7\n"
+	if err := yaml.Unmarshal([]byte(in), &chats); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(chats) != 2 || chats[0].ChatID != "111" || chats[1].ChatID != "222" || chats[1].MessageThreadID != 7 {
+		t.Errorf("got %+v, want chats 111 and 222 with topic 7", chats)
+	}
+}
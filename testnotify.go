@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+type testDelivery struct {
+	Platform string `json:"platform"`
+	Target   string `json:"target,omitempty"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+}
+
+func newTestDelivery(platform, target string, err error) testDelivery {
+	d := testDelivery{Platform: platform, Target: redactTarget(target), OK: err == nil}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	return d
+}
+
+// handleTestNotify sends a sample notification through every configured platform, bypassing
+// the digest and the Telegram queue, and reports the outcome of each delivery
+func handleTestNotify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	cfg := currentConfig()
+	// The first channel decides the per-channel routes, a placeholder uses the global ones
+	channel := ChannelConfig{ChannelID: "UC0000000000000000000000"}
+	if len(cfg.Channels) > 0 {
+		channel = cfg.Channels[0]
+	}
+	n := newNotification(ChannelConfig{ChannelID: channel.ChannelID, Label: "[TEST] " + channel.Name()}, 12300, 12345)
+	slog.InfoContext(ctx, "Sending test notification", "event", "notify", "remote_addr", r.RemoteAddr)
+
+	var results []testDelivery
+	body, err := webhookPayload(cfg.webhookPayloadTemplate, newWebhookPayloadData(n, n.Message()))
+	for _, webhook := range cfg.Webhooks {
+		if err == nil {
+			results = append(results, newTestDelivery("webhook", webhook.URL, postWebhook(ctx, webhook, body)))
+		} else {
+			results = append(results, newTestDelivery("webhook", webhook.URL, err))
+		}
+	}
+	for _, chat := range cfg.ChatIDs {
+		err := sendTelegramWithRetry(ctx, chat, escapeMarkdownV2(n.Message()), "MarkdownV2")
+		results = append(results, newTestDelivery("telegram", chat.ChatID, err))
+	}
+	if cfg.DiscordWebhookURL != "" {
+		results = append(results, newTestDelivery("discord", cfg.DiscordWebhookURL, sendDiscordNotification(ctx, n)))
+	}
+	if webhookURL := slackWebhookFor(n.ChannelID); webhookURL != "" {
+		results = append(results, newTestDelivery("slack", webhookURL, sendSlackNotification(ctx, n)))
+	}
+	if matrixConfigured() {
+		results = append(results, newTestDelivery("matrix", cfg.MatrixRoomID, sendMatrixNotification(ctx, n)))
+	}
+	if pushoverConfigured() {
+		results = append(results, newTestDelivery("pushover", pushoverMessagesURL, sendPushoverNotification(ctx, n)))
+	}
+	if emailConfigured() {
+		results = append(results, newTestDelivery("email", cfg.SMTPHost, sendEmailNotification(ctx, n)))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]testDelivery{"results": results})
+}
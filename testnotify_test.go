@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testNotify posts to handleTestNotify and returns the reported deliveries
+func testNotify(t *testing.T) []testDelivery {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handleTestNotify(rec, httptest.NewRequest(http.MethodPost, "/test-notify", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/test-notify answered %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Results []testDelivery `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body, err)
+	}
+	return resp.Results
+}
+
+func TestTestNotifyUsesChannelSlackWebhook(t *testing.T) {
+	resetPollState(t)
+	useConfig(t, `
+api_key: test
+webhook_url: https://example.com/hook
+dry_run: true
+channels:
+  - channel_id: UCxxxxxxxxxxxxxxxxxxxxxx
+    slack_webhook_url: https://hooks.slack.com/services/channel
+`)
+
+	var slack []testDelivery
+	for _, d := range testNotify(t) {
+		if d.Platform == "slack" {
+			slack = append(slack, d)
+		}
+	}
+	if len(slack) != 1 || !slack[0].OK || slack[0].Target != redactTarget("https://hooks.slack.com/services/channel") {
+		t.Errorf("slack results %+v, want one delivery to the channel's slack_webhook_url", slack)
+	}
+}
+
+func TestTestNotifyWithoutChannels(t *testing.T) {
+	resetPollState(t)
+	cfg := useConfig(t, testConfigYAML)
+	cfg.Channels = nil
+
+	results := testNotify(t)
+	if len(results) != 1 || results[0].Platform != "webhook" || !results[0].OK {
+		t.Errorf("results %+v, want the webhook delivered", results)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Values of min_change_mode
+const (
+	minChangeAny = "any"
+	minChangeAll = "all"
+)
+
+// Last count sent out per channel and when, guarded by latestCountMutex.
+// They start from the stored baseline after a restart.
+var (
+	lastNotified   = make(map[string]uint64)
+	lastNotifiedAt = make(map[string]time.Time)
+)
+
+// passesChangeThresholds applies min_change and min_change_percent to a change of diff from
+// lastCount. The percentage is diff / lastCount * 100, so a channel at 200 subscribers needs
+// a change of 2 to pass a 1% threshold while one at 10M needs 100,000. A lastCount of 0 passes
+// any percentage. With both thresholds set, min_change_mode "any" needs one of them to pass
+// and "all" needs both. The first poll never gets here, it only seeds the baseline.
+func passesChangeThresholds(lastCount, diff uint64) bool {
+	cfg := currentConfig()
+	var checks []bool
+	if cfg.MinChange > 0 {
+		checks = append(checks, diff >= cfg.MinChange)
+	}
+	if cfg.MinChangePercent > 0 {
+		checks = append(checks, lastCount == 0 || float64(diff)/float64(lastCount)*100 >= cfg.MinChangePercent)
+	}
+	if len(checks) == 0 {
+		return true
+	}
+	if cfg.MinChangeMode == minChangeAll {
+		return !slices.Contains(checks, false)
+	}
+	return slices.Contains(checks, true)
+}
+
+// shouldNotify applies the change thresholds and notify_cooldown to a change from the last
+// notified count to current. Both must pass: the difference to the last notified value has
+// to pass passesChangeThresholds, and notify_cooldown must have elapsed since the previous
+// notification.
+// Suppressed changes are not lost, they accumulate against the last notified value and are
+// reported by the next notification that passes, so flapping between two adjacent counts
+// stays silent while a steady trend is still announced.
+func shouldNotify(channelID string, lastCount, current uint64) bool {
+	diff := current - lastCount
+	if current < lastCount {
+		diff = lastCount - current
+	}
+	if diff == 0 || !passesChangeThresholds(lastCount, diff) {
+		return false
+	}
+
+	if currentConfig().notifyCooldown > 0 {
+		if at, ok := lastNotifiedAt[channelID]; ok && time.Since(at) < currentConfig().notifyCooldown {
+			return false
+		}
+	}
+	return true
+}
+
+func recordNotified(channelID string, count uint64) {
+	lastNotified[channelID] = count
+	lastNotifiedAt[channelID] = time.Now()
+}
+
+// Platforms that accept a cooldown
+var cooldownPlatforms = []string{"webhook", "telegram", "discord", "slack", "matrix", "pushover", "email"}
+
+// Cooldowns maps a platform to the shortest time between its notifications about a channel
+type Cooldowns map[string]string
+
+func parseCooldowns(c Cooldowns) (map[string]time.Duration, error) {
+	cooldowns := make(map[string]time.Duration, len(c))
+	for platform, value := range c {
+		if !slices.Contains(cooldownPlatforms, platform) {
+			return nil, fmt.Errorf("unknown platform %q", platform)
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", platform, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("%s must not be negative, got %v", platform, d)
+		}
+		cooldowns[platform] = d
+	}
+	return cooldowns, nil
+}
+
+// When each platform last got a change notification, keyed by platform and channel ID
+var (
+	platformNotifiedAt      = make(map[[2]string]time.Time)
+	platformNotifiedAtMutex sync.Mutex
+)
+
+// platformReady applies the cooldown of platform to a change notification about channelID
+// and records the notification when it passes. It runs after shouldNotify, so a platform
+// cooldown can only suppress further notifications, never add any. The changes skipped by
+// a platform are not resent, its next notification carries the delta since the previous
+// notification of any platform. Milestone, target, upload, livestream and alert messages as
+// well as digests bypass the cooldown.
+func platformReady(platform, channelID string) bool {
+	cooldown := currentConfig().platformCooldowns[platform]
+	if cooldown <= 0 {
+		return true
+	}
+
+	platformNotifiedAtMutex.Lock()
+	defer platformNotifiedAtMutex.Unlock()
+	key := [2]string{platform, channelID}
+	if at, ok := platformNotifiedAt[key]; ok && time.Since(at) < cooldown {
+		slog.Debug("Platform cooldown active, skipping notification", "event", "notify", "platform", platform, "channel_id", channelID)
+		return false
+	}
+	platformNotifiedAt[key] = time.Now()
+	return true
+}
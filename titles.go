@@ -0,0 +1,62 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Channel titles rarely change, they are refreshed with the next poll after this long
+const titleRefreshInterval = 24 * time.Hour
+
+type cachedTitle struct {
+	title     string
+	fetchedAt time.Time
+}
+
+var (
+	channelTitles      = make(map[string]cachedTitle)
+	channelTitlesMutex sync.Mutex
+)
+
+// channelParts returns the parts to request for channels, adding snippet when one of
+// their titles is missing or stale. Channels.List costs the same quota whatever the parts.
+func channelParts(channels []ChannelConfig) []string {
+	parts := currentConfig().YouTubeParts
+	if slices.Contains(parts, "snippet") {
+		return parts
+	}
+
+	channelTitlesMutex.Lock()
+	defer channelTitlesMutex.Unlock()
+	for _, channel := range channels {
+		cached, ok := channelTitles[channel.ChannelID]
+		if !ok || time.Since(cached.fetchedAt) > titleRefreshInterval {
+			return append(slices.Clip(parts), "snippet")
+		}
+	}
+	return parts
+}
+
+func rememberTitle(channelID, title string) {
+	if title == "" {
+		return
+	}
+	channelTitlesMutex.Lock()
+	channelTitles[channelID] = cachedTitle{title: title, fetchedAt: time.Now()}
+	channelTitlesMutex.Unlock()
+}
+
+// forgetTitles drops the cached titles so the next poll requests them again
+func forgetTitles() {
+	channelTitlesMutex.Lock()
+	clear(channelTitles)
+	channelTitlesMutex.Unlock()
+}
+
+// channelTitle returns the YouTube title of the channel if it was fetched
+func channelTitle(channelID string) string {
+	channelTitlesMutex.Lock()
+	defer channelTitlesMutex.Unlock()
+	return channelTitles[channelID].title
+}
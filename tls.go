@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net"
+	"net/http"
+)
+
+func (c *Config) tlsEnabled() bool {
+	return c.TLSCertFile != ""
+}
+
+// serve runs server until it is shut down, with TLS when tls_cert_file and tls_key_file are set
+func serve(server *http.Server, cfg *Config) error {
+	if cfg.tlsEnabled() {
+		return server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	return server.ListenAndServe()
+}
+
+// newRedirectServer listens on tls_redirect_addr and sends every plain HTTP request to the
+// same path on the HTTPS listener
+func newRedirectServer(cfg *Config) *http.Server {
+	_, port, _ := net.SplitHostPort(cfg.ListenAddr)
+	return &http.Server{
+		Addr: cfg.TLSRedirectAddr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if port != "443" {
+				host = net.JoinHostPort(host, port)
+			}
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+		}),
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// tokenEncryptionKeyEnv names the environment variable holding the token passphrase
+const tokenEncryptionKeyEnv = "TOKEN_ENCRYPTION_KEY"
+
+// Encrypted token files are tokenMagic | salt | nonce | AES-GCM ciphertext
+var tokenMagic = []byte("YTNTOKEN1\n")
+
+const tokenSaltSize = 16
+
+func tokenEncryptionKey() string {
+	return os.Getenv(tokenEncryptionKeyEnv)
+}
+
+func isEncryptedToken(data []byte) bool {
+	return bytes.HasPrefix(data, tokenMagic)
+}
+
+// deriveTokenKey stretches the passphrase into an AES-256 key
+func deriveTokenKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+}
+
+func encryptToken(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, tokenSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	gcm, err := tokenCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte{}, tokenMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, tokenMagic), nil
+}
+
+func decryptToken(data []byte, passphrase string) ([]byte, error) {
+	data = bytes.TrimPrefix(data, tokenMagic)
+	if len(data) < tokenSaltSize {
+		return nil, errors.New("encrypted token is truncated")
+	}
+	salt, data := data[:tokenSaltSize], data[tokenSaltSize:]
+
+	gcm, err := tokenCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("encrypted token is truncated")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, tokenMagic)
+	if err != nil {
+		return nil, errors.New("unable to decrypt token, wrong " + tokenEncryptionKeyEnv + "?")
+	}
+	return plaintext, nil
+}
+
+func tokenCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := deriveTokenKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
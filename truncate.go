@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Longest message accepted by each platform, in characters. For Discord it is the embed
+// description and for Slack a section block.
+var platformMessageLimits = map[string]int{
+	"telegram": 4096,
+	"discord":  4096,
+	"slack":    3000,
+}
+
+// Shortest max_message_length, the ellipsis and closed MarkdownV2 entities need some room
+const minMessageLength = 16
+
+const ellipsis = "…"
+
+// messageLimit is the configured max_message_length of platform or its default
+func messageLimit(platform string) int {
+	if limit, ok := currentConfig().MaxMessageLength[platform]; ok {
+		return limit
+	}
+	return platformMessageLimits[platform]
+}
+
+func validateMessageLimits(limits map[string]int) error {
+	for platform, limit := range limits {
+		if _, ok := platformMessageLimits[platform]; !ok {
+			return fmt.Errorf("max_message_length: unknown platform %q", platform)
+		}
+		if limit < minMessageLength {
+			return fmt.Errorf("max_message_length of %s must be at least %d, got %d", platform, minMessageLength, limit)
+		}
+	}
+	return nil
+}
+
+// truncateMessage shortens s to limit characters, ending with an ellipsis when it was cut
+func truncateMessage(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + ellipsis
+}
+
+// truncateMarkdownV2 shortens an escaped MarkdownV2 message to limit characters. The message is
+// never cut between a backslash and the character it escapes or inside an inline link, and
+// bold or italic entities left open at the cut are closed after the ellipsis.
+func truncateMarkdownV2(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+
+	var (
+		open    []rune // entity markers not closed yet, innermost last
+		inLink  bool
+		cut     int
+		cutOpen []rune
+	)
+	// Room for the ellipsis and a closing marker for bold and italic
+	budget := limit - 3
+	for i := 0; i <= budget && i < len(runes); i++ {
+		if !inLink {
+			cut, cutOpen = i, slices.Clone(open)
+		}
+		switch r := runes[i]; {
+		case r == '\\':
+			i++
+		case r == '[':
+			inLink = true
+		case r == ')' && inLink:
+			inLink = false
+		case (r == '*' || r == '_') && !inLink:
+			if j := slices.Index(open, r); j >= 0 {
+				open = slices.Delete(open, j, j+1)
+			} else {
+				open = append(open, r)
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(string(runes[:cut]))
+	b.WriteString(ellipsis)
+	for i := len(cutOpen) - 1; i >= 0; i-- {
+		b.WriteRune(cutOpen[i])
+	}
+	return b.String()
+}
@@ -1,111 +1,281 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
-	"mime/multipart"
+	"html/template"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"runtime/debug"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/youtube/v3"
-	"gopkg.in/yaml.v3"
 )
 
-// Configuration
-type Config struct {
-	ClientID     string   `yaml:"client_id"`
-	ClientSecret string   `yaml:"client_secret"`
-	RedirectURL  string   `yaml:"redirect_url"`
-	WebhookURL   string   `yaml:"webhook_url"`
-	ChannelID    string   `yaml:"channel_id"`
-	BotKey       string   `yaml:"bot_key"`
-	ChatIDs      []string `yaml:"chat_ids"`
-	SleepTime    int      `yaml:"sleep_time"`
-}
+// The YouTube API accepts at most 50 IDs per Channels.List request
+const maxChannelsPerRequest = 50
 
-var config *Config
+// oauthCallbackPath is where Google redirects after login, redirect_url must point to it
+const oauthCallbackPath = "/oauth2callback"
 
 var (
 	oauthConfig      *oauth2.Config
-	state            = "randomstatestring"
 	token            *oauth2.Token
 	tokenMutex       sync.Mutex
-	latestCount      int64
+	latestCount      map[string]int64
 	latestCountMutex sync.Mutex
 )
 
-func init() {
-	// Read from yaml file
-	data, err := os.ReadFile("config.yaml")
-	if err != nil {
-		panic(fmt.Sprintf("Read config file error: %v", err))
+func main() {
+	configPath := flag.String("config", "", "path of the configuration file (default $CONFIG_PATH or "+defaultConfigPath+")")
+	once := flag.Bool("once", false, "print the current channel statistics as JSON and exit")
+	initConfig := flag.Bool("init", false, "write a commented "+defaultConfigPath+" template to the current directory and exit")
+	deviceAuth := flag.Bool("device-auth", false, "log in with a code entered on another device, save token.json and exit")
+	flag.Parse()
+
+	if *initConfig {
+		if err := writeConfigTemplate(defaultConfigPath); err != nil {
+			slog.Error("Unable to write configuration template", "error", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %s, fill in the credentials, channels and a webhook\n", defaultConfigPath)
+		return
 	}
 
-	config = &Config{}
-	decoder := yaml.NewDecoder(bytes.NewReader(data))
-	err = decoder.Decode(config)
-	if err != nil {
-		panic(fmt.Sprintf("Decode config file error: %v", err))
+	path := *configPath
+	if path == "" {
+		path = os.Getenv("CONFIG_PATH")
+	}
+	if path == "" {
+		path = defaultConfigPath
 	}
 
-	if config.ClientID == "" || config.ClientSecret == "" || config.RedirectURL == "" || config.WebhookURL == "" || config.ChannelID == "" {
-		panic("Invalid configuration")
+	cfg, err := loadConfig(path)
+	if err != nil {
+		slog.Error("Unable to load configuration", "path", path, "error", err)
+		os.Exit(1)
 	}
+	setConfig(cfg)
+	slog.SetDefault(cfg.logger)
+	build := currentVersion()
+	slog.Info("Starting youtube-notification", "version", build.Version, "commit", build.Commit, "build_date", build.BuildDate, "go_version", build.GoVersion)
+	checkQuotaBudget(cfg)
+	configureHTTPClient(cfg)
 
 	oauthConfig = &oauth2.Config{
-		ClientID:     config.ClientID,
-		ClientSecret: config.ClientSecret,
-		RedirectURL:  config.RedirectURL,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
 		Scopes:       []string{youtube.YoutubeReadonlyScope},
 		Endpoint:     google.Endpoint,
 	}
-}
 
-func main() {
-	// Load token if available
-	var err error
-	token, err = loadToken()
-	if err != nil {
-		log.Println("No token found, please authenticate via /login")
+	if *deviceAuth {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := runDeviceAuth(ctx, os.Stdout)
+		stop()
+		if err != nil {
+			slog.Error("Device login failed", "event", "oauth", "error", err)
+			os.Exit(1)
+		}
+		fmt.Println("Login successful, token.json was saved")
+		return
 	}
 
+	switch {
+	case cfg.APIKey != "":
+		slog.Info("Using an API key for public statistics, /login is disabled")
+	case cfg.ServiceAccountFile != "":
+		serviceAccount, err = loadServiceAccount(cfg.ServiceAccountFile)
+		if err != nil {
+			slog.Error("Unable to load service account", "path", cfg.ServiceAccountFile, "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Using service account credentials, /login is disabled", "path", cfg.ServiceAccountFile)
+	default:
+		// Load token if available
+		token, err = loadToken()
+		if errors.Is(err, errCorruptToken) {
+			slog.Error("token.json is corrupt and was moved to token.json.bak, please authenticate again via /login", "error", err)
+		} else if err != nil {
+			slog.Warn("No token found, please authenticate via /login", "error", err)
+		}
+		if token != nil && cfg.ValidateTokenOnStart {
+			validateToken(context.Background())
+		}
+	}
+
+	if hasUnresolvedHandles() && haveCredentials() {
+		fetcher, err := newFetcher(context.Background())
+		if err == nil {
+			err = resolveChannelHandles(context.Background(), fetcher)
+		}
+		if err != nil {
+			slog.Error("Unable to resolve channel handles", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if *once {
+		if err := printStatsOnce(context.Background(), os.Stdout); err != nil {
+			slog.Error("Unable to fetch channel statistics", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.DBPath != "" {
+		if err := openHistory(cfg.DBPath); err != nil {
+			slog.Error("Unable to open history database", "error", err)
+			os.Exit(1)
+		}
+		defer historyDB.Close()
+	}
+	restoreLatestCounts()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	http.HandleFunc("/", handleHome)
-	http.HandleFunc("/login", handleLogin)
-	http.HandleFunc("/oauth2callback", handleOAuth2Callback)
-	go monitorSubscriberCount()
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	if usesOAuthLogin() {
+		http.HandleFunc("/login", handleLogin)
+		http.HandleFunc(oauthCallbackPath, handleOAuth2Callback)
+	}
+	http.HandleFunc("/healthz", handleHealth)
+	http.HandleFunc("/readyz", handleReady)
+	http.HandleFunc("/version", handleVersion)
+	http.HandleFunc("/status", requireBearerToken(handleStatus))
+	http.HandleFunc("/dashboard", requireBearerToken(handleDashboard))
+	http.HandleFunc("/refresh", requireCredentials(handleRefresh))
+	http.HandleFunc("/notifications", requireBearerToken(handleNotifications))
+	http.HandleFunc("/test-notify", requireCredentials(handleTestNotify))
+	if cfg.MetricsEnabled {
+		metricsPath := cfg.MetricsPath
+		if metricsPath == "" {
+			metricsPath = defaultMetricsPath
+		}
+		http.Handle(metricsPath, promhttp.Handler())
+	}
+
+	server := &http.Server{Addr: cfg.ListenAddr, Handler: requireBasicAuth(http.DefaultServeMux)}
+	go func() {
+		if err := serve(server, cfg); err != nil && err != http.ErrServerClosed {
+			slog.Error("HTTP server failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	var redirectServer *http.Server
+	if cfg.TLSRedirectAddr != "" {
+		redirectServer = newRedirectServer(cfg)
+		go func() {
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("HTTPS redirect server failed", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	go watchReload(ctx, path)
+	go runDigest(ctx)
+	go runWebhookQueue(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		monitorSubscriberCount(ctx, newFetcher)
+		close(done)
+	}()
+
+	<-ctx.Done()
+	slog.Info("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Error shutting down HTTP server", "error", err)
+	}
+	if redirectServer != nil {
+		redirectServer.Shutdown(shutdownCtx)
+	}
+
+	select {
+	case <-done:
+	case <-shutdownCtx.Done():
+		slog.Warn("Monitor did not stop in time")
+	}
+
+	flushNotifications(notificationFlushTimeout)
 }
 
+// loginSuccessTemplate confirms the login without showing any token material
+var loginSuccessTemplate = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Login successful</title></head>
+<body>
+<h1>Login successful</h1>
+<p>YouTube access was granted, subscriber monitoring is now active.</p>
+{{if not .Refreshable}}<p>No refresh token was issued, you may need to log in again when the access expires.</p>{{end}}
+<p>You can close this page.</p>
+</body>
+</html>
+`))
+
+// handleHome links the dashboard, and /login unless an API key or service account replaces it
 func handleHome(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintf(w, `<html><body><a href="/login">Login with YouTube</a></body></html>`)
+	links := `<a href="/dashboard">Dashboard</a>`
+	if usesOAuthLogin() {
+		links = `<a href="/login">Login with YouTube</a> | ` + links
+	}
+	fmt.Fprintf(w, `<html><body>%s</body></html>`, links)
 }
 
 func handleLogin(w http.ResponseWriter, r *http.Request) {
-	url := oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "consent"))
+	state, verifier, err := newOAuthState()
+	if err != nil {
+		slog.Error("Failed to generate OAuth state", "event", "oauth", "error", err)
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+
+	url := oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "consent"), oauth2.S256ChallengeOption(verifier))
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
 func handleOAuth2Callback(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Query().Get("state") != state {
-		http.Error(w, "State parameter doesn't match", http.StatusBadRequest)
+	verifier, ok := consumeOAuthState(r.URL.Query().Get("state"))
+	if !ok {
+		slog.Warn("OAuth callback with unknown or expired state", "event", "oauth", "remote_addr", r.RemoteAddr)
+		http.Error(w, "State parameter doesn't match, please start again from /login", http.StatusBadRequest)
 		return
 	}
 
 	code := r.URL.Query().Get("code")
-	tok, err := oauthConfig.Exchange(context.Background(), code)
+	tok, err := exchangeWithRetry(r.Context(), code, verifier)
 	if err != nil {
-		http.Error(w, "Failed to exchange token: "+err.Error(), http.StatusInternalServerError)
+		slog.Error("Failed to exchange token", "event", "oauth", "error", err)
+		if isTransientOAuthError(err) {
+			http.Error(w, "Google could not be reached to complete the login, this is usually temporary. Please try again from /login.", http.StatusBadGateway)
+		} else {
+			http.Error(w, "Google rejected the authorization code, it may have expired or been used already. Please start again from /login.", http.StatusBadRequest)
+		}
 		return
 	}
 
+	slog.Info("OAuth login successful", "event", "oauth", "expiry", tok.Expiry, "refresh_token", tok.RefreshToken != "")
+
 	// Store the token for later use (including refresh token)
 	saveToken(tok)
 
@@ -113,152 +283,459 @@ func handleOAuth2Callback(w http.ResponseWriter, r *http.Request) {
 	token = tok
 	tokenMutex.Unlock()
 
-	fmt.Fprintf(w, "Login successful! Token is %v", tok)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := loginSuccessTemplate.Execute(w, struct{ Refreshable bool }{tok.RefreshToken != ""}); err != nil {
+		slog.Error("Failed to render login page", "event", "oauth", "error", err)
+	}
 }
 
+// errCorruptToken is returned by loadToken when token.json cannot be decoded
+var errCorruptToken = errors.New("token.json is corrupt")
+
 func loadToken() (*oauth2.Token, error) {
-	file, err := os.Open("token.json")
+	data, err := os.ReadFile("token.json")
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+
+	key := tokenEncryptionKey()
+	if isEncryptedToken(data) {
+		if key == "" {
+			return nil, errors.New("token.json is encrypted but " + tokenEncryptionKeyEnv + " is not set")
+		}
+		if data, err = decryptToken(data, key); err != nil {
+			return nil, err
+		}
+	} else if key != "" {
+		slog.Info("token.json is stored in plaintext, it will be encrypted on next save")
+	}
+
 	tok := &oauth2.Token{}
-	err = json.NewDecoder(file).Decode(tok)
-	return tok, err
+	if err := json.Unmarshal(data, tok); err != nil {
+		// Keep the damaged file around for inspection, a later saveToken would overwrite it
+		if backupErr := os.Rename("token.json", "token.json.bak"); backupErr != nil {
+			slog.Error("Unable to back up corrupt token.json", "error", backupErr)
+		}
+		return nil, fmt.Errorf("%w: %v", errCorruptToken, err)
+	}
+	return tok, nil
 }
 
 func saveToken(tok *oauth2.Token) {
-	file, err := os.Create("token.json")
+	data, err := json.Marshal(tok)
 	if err != nil {
-		log.Fatalf("Unable to cache oauth token: %v", err)
+		slog.Error("Unable to encode oauth token", "error", err)
+		return
+	}
+
+	if key := tokenEncryptionKey(); key != "" {
+		if data, err = encryptToken(data, key); err != nil {
+			slog.Error("Unable to encrypt oauth token", "error", err)
+			os.Exit(1)
+		}
 	}
-	defer file.Close()
-	json.NewEncoder(file).Encode(tok)
+
+	if err := writeFileAtomic("token.json", data, 0600); err != nil {
+		slog.Error("Unable to cache oauth token", "error", err)
+		os.Exit(1)
+	}
+}
+
+// pollInterval returns the configured time between two checks
+func pollInterval() time.Duration {
+	return currentConfig().pollInterval
 }
 
-func monitorSubscriberCount() {
-	sleepTime := config.SleepTime
-	if sleepTime == 0 {
-		sleepTime = 60
+// apiTimeout bounds a single YouTube API call or token refresh. It defaults to half the
+// poll interval, at most 30 seconds, so a stalled call never delays the next poll.
+func apiTimeout() time.Duration {
+	cfg := currentConfig()
+	if cfg.apiTimeout > 0 {
+		return cfg.apiTimeout
 	}
+	return min(cfg.pollInterval/2, 30*time.Second)
+}
 
-	for {
-		log.Printf("Sleeping for %d seconds...", sleepTime)
-		time.Sleep(time.Duration(sleepTime) * time.Second) // Adjust the interval as needed
-		log.Printf("Check subscriber count...")
-		tokenMutex.Lock()
-		if token == nil {
-			tokenMutex.Unlock()
-			log.Printf("No token found, skipping check")
-			continue
+// monitorSubscriberCount polls every poll interval until ctx is done, reading YouTube through
+// the fetchers made by connect
+func monitorSubscriberCount(ctx context.Context, connect fetcherFactory) {
+	monitorRunning.Store(true)
+	defer monitorRunning.Store(false)
+
+	backoff := &pollBackoff{}
+	started := false
+	for first := true; ; first = false {
+		delay := withPollJitter(backoff.delay(pollInterval()), first)
+		slog.Debug("Sleeping", "event", "poll", "delay", delay.String())
+		recordMonitorHeartbeat(delay)
+		select {
+		case <-ctx.Done():
+			failRefreshes(ctx.Err())
+			return
+		case <-time.After(delay): // Adjust the interval as needed
+		case <-refreshSignal:
+			slog.Info("Refresh requested", "event", "poll")
 		}
 
-		// Refresh the token if expired
-		if token.Expiry.Before(time.Now()) {
-			newToken, err := oauthConfig.TokenSource(context.Background(), token).Token()
-			if err != nil {
-				log.Printf("Error refreshing token: %v", err)
-				tokenMutex.Unlock()
-				continue
+		// Requests arriving from now on wait for the next poll
+		waiters := takeRefreshWaiters()
+		stats, err := pollRecovered(ctx, backoff, connect)
+		completeRefreshes(waiters, refreshResult{stats: stats, err: err})
+
+		// The first successful poll doubles as a check of the notification settings
+		if !started && err == nil && len(stats) > 0 {
+			started = true
+			if currentConfig().NotifyOnStart {
+				sendStartNotification(ctx, stats)
 			}
-			token = newToken
-			saveToken(token) // Save the new token with a new expiry time
 		}
+	}
+}
 
-		client := oauthConfig.Client(context.Background(), token)
-		tokenMutex.Unlock()
+// pollRecovered runs pollOnce and turns a panic into an error, so a bug triggered by one
+// response backs the loop off instead of silently ending all polling
+func pollRecovered(ctx context.Context, backoff *pollBackoff, connect fetcherFactory) (stats []ChannelStats, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stack := debug.Stack()
+			if wp, ok := r.(workerPanic); ok {
+				r, stack = wp.value, wp.stack
+			}
+			slog.Error("Recovered from panic in monitor loop", "event", "poll", "panic", r, "stack", string(stack))
+			err = fmt.Errorf("panic during poll: %v", r)
+			backoff.record(err)
+		}
+	}()
+	return pollOnce(ctx, backoff, connect)
+}
 
-		service, err := youtube.New(client)
+// pollOnce performs a single check of every channel and returns the statistics that were fetched
+func pollOnce(ctx context.Context, backoff *pollBackoff, connect fetcherFactory) ([]ChannelStats, error) {
+	ctx = newPollContext(ctx)
+	slog.DebugContext(ctx, "Check subscriber count", "event", "poll")
+	fetcher, err := connect(ctx)
+	if errors.Is(err, errNoToken) {
+		slog.WarnContext(ctx, "No token found, skipping check", "event", "poll")
+		return nil, err
+	}
+	if err != nil {
+		if !isInvalidGrant(err) {
+			slog.ErrorContext(ctx, "Error preparing YouTube client", "event", "poll", "error", err)
+			backoff.record(err)
+		}
+		return nil, err
+	}
+	// With an OAuth login the handles can only be resolved once the token exists
+	if err := resolveChannelHandles(ctx, fetcher); err != nil {
+		slog.ErrorContext(ctx, "Error resolving channel handles", "event", "poll", "error", err)
+	}
+	stats, err := pollChannels(ctx, fetcher)
+	backoff.record(err)
+	return stats, err
+}
+
+// fetcherFactory returns the StatsFetcher used for one poll, newFetcher outside of tests
+type fetcherFactory func(ctx context.Context) (StatsFetcher, error)
+
+var errNoToken = errors.New("no oauth token, authenticate via /login")
+
+// newFetcher returns a StatsFetcher using the API key or service account if configured,
+// otherwise the current token, refreshing the token first if it is about to expire
+func newFetcher(ctx context.Context) (StatsFetcher, error) {
+	if !usesOAuthLogin() {
+		client := apiKeyClient()
+		if serviceAccount != nil {
+			client = serviceAccountClient(ctx)
+		}
+		service, err := newYouTubeService(ctx, client)
 		if err != nil {
-			log.Printf("Error creating YouTube service: %v", err)
-			continue
+			return nil, fmt.Errorf("creating YouTube service: %w", err)
 		}
+		return newYouTubeFetcher(service), nil
+	}
+
+	tokenMutex.Lock()
+	defer tokenMutex.Unlock()
+	if token == nil {
+		return nil, errNoToken
+	}
 
-		call := service.Channels.List([]string{"statistics"}).Id(config.ChannelID)
-		response, err := call.Do()
+	// Refresh the token shortly before it expires, so clock drift and slow requests do not
+	// make a call with an expired token
+	if tokenNeedsRefresh(token, time.Now(), currentConfig().tokenRefreshSkew) {
+		refreshCtx, cancel := context.WithTimeout(ctx, apiTimeout())
+		// Without an access token the token source refreshes even if the token is still valid
+		stale := *token
+		stale.AccessToken = ""
+		newToken, err := oauthConfig.TokenSource(oauthContext(refreshCtx), &stale).Token()
+		cancel()
 		if err != nil {
-			log.Printf("Error fetching channel statistics: %v", err)
-			continue
+			if isInvalidGrant(err) {
+				clearRevokedToken(err)
+			}
+			return nil, fmt.Errorf("refreshing token: %w", err)
 		}
+		token = newToken
+		saveToken(token) // Save the new token with a new expiry time
+	}
+
+	service, err := newYouTubeService(ctx, youtubeClient(ctx, token))
+	if err != nil {
+		return nil, fmt.Errorf("creating YouTube service: %w", err)
+	}
+	return newYouTubeFetcher(service), nil
+}
 
-		if len(response.Items) == 0 {
-			log.Printf("No channel found with ID: %s", config.ChannelID)
+// pollChannels checks every configured channel once and returns the statistics fetched
+// along with the last error
+func pollChannels(ctx context.Context, fetcher StatsFetcher) ([]ChannelStats, error) {
+	channels := slices.DeleteFunc(slices.Clone(currentConfig().Channels), func(channel ChannelConfig) bool {
+		return channel.ChannelID == ""
+	})
+	batches := channelBatches(channels)
+	concurrency := currentConfig().PollConcurrency
+
+	// Every batch is fetched before any count is compared or notified about
+	results := make([]map[string]ChannelStats, len(batches))
+	errs := make([]error, len(batches))
+	forEachBounded(len(batches), concurrency, func(i int) {
+		results[i], errs[i] = fetchChannelStats(ctx, fetcher, batches[i])
+	})
+
+	var all []ChannelStats
+	var found []ChannelConfig
+	var pollErr error
+	for i, batch := range batches {
+		if errs[i] != nil {
+			pollErr = errs[i]
 			continue
 		}
+		for _, channel := range batch {
+			s, ok := results[i][channel.ChannelID]
+			if !ok {
+				slog.WarnContext(ctx, "No channel found", "event", "poll", "channel_id", channel.ChannelID)
+				continue
+			}
+			updateChannel(ctx, channel, s)
+			all = append(all, s)
+			found = append(found, channel)
+		}
+	}
 
-		subscriberCount := response.Items[0].Statistics.SubscriberCount
-		latestCountMutex.Lock()
+	// A partial sum would look like a drop, only aggregate polls that reached every channel
+	if currentConfig().Aggregate && pollErr == nil && len(all) == len(channels) {
+		updateAggregate(ctx, all)
+	}
 
-		log.Printf("Get subscriberCount from YouTube %d", subscriberCount)
+	// Uploads and live streams take a request per channel
+	forEachBounded(len(found), concurrency, func(i int) {
+		checkUploads(ctx, fetcher, found[i], all[i].UploadsPlaylistID)
+		checkLivestream(ctx, fetcher, found[i])
+	})
+	return all, pollErr
+}
 
-		if latestCount == 0 {
-			latestCountBytes, _ := os.ReadFile("latestCount.txt")
-			latestCount, _ = strconv.ParseInt(string(latestCountBytes), 10, 64)
-		}
+// workerPanic is a panic of a forEachBounded call with the stack of the goroutine it happened in
+type workerPanic struct {
+	value any
+	stack []byte
+}
 
-		if int64(subscriberCount) != latestCount {
-			latestCount = int64(subscriberCount)
-			_ = os.WriteFile("latestCount.txt", []byte(strconv.FormatInt(latestCount, 10)), 0644)
+// forEachBounded calls fn for 0 to n-1 with at most limit calls running at once. A panic in fn
+// is raised again as workerPanic in the calling goroutine once all calls returned, so
+// pollRecovered catches it.
+func forEachBounded(n, limit int, fn func(i int)) {
+	var wg sync.WaitGroup
+	var panicOnce sync.Once
+	var panicked *workerPanic
+	sem := make(chan struct{}, max(limit, 1))
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer func() {
+				if r := recover(); r != nil {
+					panicOnce.Do(func() { panicked = &workerPanic{value: r, stack: debug.Stack()} })
+				}
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+	if panicked != nil {
+		panic(*panicked)
+	}
+}
 
-			// sendWebhookNotification(subscriberCount)
-			sendTelegramNotification(subscriberCount)
-		} else {
-			log.Printf("Subscriber count is the same as before %d", subscriberCount)
-		}
-		latestCountMutex.Unlock()
+// channelBatches splits channels into groups small enough for a single Channels.List call
+func channelBatches(channels []ChannelConfig) [][]ChannelConfig {
+	var batches [][]ChannelConfig
+	for len(channels) > maxChannelsPerRequest {
+		batches = append(batches, channels[:maxChannelsPerRequest])
+		channels = channels[maxChannelsPerRequest:]
+	}
+	if len(channels) > 0 {
+		batches = append(batches, channels)
 	}
+	return batches
 }
 
-func sendWebhookNotification(subscriberCount int64) {
-	fmt.Println("Sending webhook notification with subscriber count:", subscriberCount)
+func fetchChannelStats(ctx context.Context, fetcher StatsFetcher, channels []ChannelConfig) (map[string]ChannelStats, error) {
+	stats, err := fetcher.FetchChannelStats(ctx, channels)
+	if err != nil {
+		ids := make([]string, len(channels))
+		for i, channel := range channels {
+			ids[i] = channel.ChannelID
+		}
+		slog.ErrorContext(ctx, "Error fetching channel statistics", "event", "poll", "channel_ids", ids, "error", err)
+		return nil, err
+	}
+
+	recordSuccessfulPoll()
+	return stats, nil
+}
 
-	payload := map[string]interface{}{
-		"subscriber_count": subscriberCount,
+// updateChannel compares the fetched statistics of channel with the stored ones and notifies
+// about changes
+func updateChannel(ctx context.Context, channel ChannelConfig, s ChannelStats) {
+	rememberTitle(channel.ChannelID, s.Title)
+	if !trackHiddenSubscribers(ctx, channel, s.HiddenSubscriberCount) {
+		updateSubscriberCount(ctx, channel, s.SubscriberCount)
+		recordHistory(s)
+	}
+	if watchingMetric(metricViews) {
+		updateMetric(ctx, channel, metricViews, s.ViewCount)
 	}
-	body, _ := json.Marshal(payload)
+	if watchingMetric(metricVideos) {
+		updateMetric(ctx, channel, metricVideos, s.VideoCount)
+	}
+	// An absent comment count reads as 0, which is not a drop worth reporting
+	if watchingMetric(metricComments) && s.CommentCount > 0 {
+		updateMetric(ctx, channel, metricComments, s.CommentCount)
+	}
+	recordChannelMetrics(s)
+}
 
-	resp, err := http.Post(config.WebhookURL, "application/json", bytes.NewBuffer(body))
-	if err != nil {
-		log.Printf("Error sending webhook notification: %v", err)
+func updateSubscriberCount(ctx context.Context, channel ChannelConfig, subscriberCount uint64) {
+	slog.InfoContext(ctx, "Got subscriber count from YouTube", "event", "poll", "channel_id", channel.ChannelID, "subscriber_count", subscriberCount)
+
+	// Everything is sent without latestCountMutex, /healthz and /status must not wait for a slow platform
+	previous, known, base, notify := recordSubscriberCount(ctx, channel, subscriberCount)
+	checkMilestones(ctx, channel, uint64(previous), subscriberCount, known)
+	checkTarget(ctx, channel, subscriberCount)
+	if !known {
 		return
 	}
-	defer resp.Body.Close()
+	if int64(subscriberCount) < previous {
+		checkDrop(ctx, channel, uint64(previous), subscriberCount)
+	}
+	if !notify {
+		return
+	}
+
+	n := newNotification(channel, base, subscriberCount)
+	n.Windows = subscriberWindows(channel.ChannelID, subscriberCount)
+	dispatchNotification(ctx, n)
+}
+
+// recordSubscriberCount stores subscriberCount as the latest count of the channel. It returns the
+// previous count, whether there was one, the count of the last notification and whether the
+// change is to be notified, which is then recorded as sent.
+func recordSubscriberCount(ctx context.Context, channel ChannelConfig, subscriberCount uint64) (previous int64, known bool, base uint64, notify bool) {
+	latestCountMutex.Lock()
+	defer latestCountMutex.Unlock()
+
+	if latestCount == nil {
+		latestCount = loadLatestCounts()
+	}
+
+	previous, known = latestCount[channel.ChannelID]
+	// The first poll without a stored baseline only seeds it, there is nothing to compare against
+	if !known {
+		slog.InfoContext(ctx, "Seeding subscriber count", "event", "poll", "channel_id", channel.ChannelID, "subscriber_count", subscriberCount)
+		latestCount[channel.ChannelID] = int64(subscriberCount)
+		saveLatestCounts(latestCount)
+		return previous, known, 0, false
+	}
+
+	base, ok := lastNotified[channel.ChannelID]
+	if !ok {
+		base = uint64(previous)
+	}
+
+	if int64(subscriberCount) == previous && subscriberCount == base {
+		slog.DebugContext(ctx, "Subscriber count unchanged", "event", "poll", "channel_id", channel.ChannelID, "subscriber_count", subscriberCount)
+		return previous, known, base, false
+	}
+
+	if int64(subscriberCount) != previous {
+		latestCount[channel.ChannelID] = int64(subscriberCount)
+		saveLatestCounts(latestCount)
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Unexpected status code from webhook: %d", resp.StatusCode)
+	if !watchingMetric(metricSubscribers) {
+		return previous, known, base, false
 	}
+	if currentConfig().AggregateOnly && channel.ChannelID != aggregateChannelID {
+		return previous, known, base, false
+	}
+	if !shouldNotify(channel.ChannelID, base, subscriberCount) {
+		slog.DebugContext(ctx, "Subscriber count change suppressed", "event", "poll", "channel_id", channel.ChannelID,
+			"subscriber_count", subscriberCount, "last_notified", base)
+		return previous, known, base, false
+	}
+	recordNotified(channel.ChannelID, subscriberCount)
+	return previous, known, base, true
 }
 
-func sendTelegramNotification(subscriberCount uint64) {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", config.BotKey)
-	method := "POST"
-
-	for _, chatID := range config.ChatIDs {
-		payload := &bytes.Buffer{}
-		writer := multipart.NewWriter(payload)
-		_ = writer.WriteField("text", fmt.Sprintf("Subscriber count: %d", subscriberCount))
-		_ = writer.WriteField("chat_id", chatID)
-		_ = writer.WriteField("caption", "")
-		_ = writer.WriteField("parse_mode", "MarkdownV2")
-		_ = writer.WriteField("disable_notification", "true")
-		err := writer.Close()
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
+// restoreLatestCounts loads the baselines saved by the previous run so the first poll after a
+// restart computes a delta instead of seeding. Channels missing from latestCount.json fall back
+// to their last row in the history database.
+func restoreLatestCounts() {
+	latestCountMutex.Lock()
+	defer latestCountMutex.Unlock()
 
-		client := &http.Client{}
-		req, err := http.NewRequest(method, url, payload)
-		if err != nil {
-			fmt.Println(err)
-			return
+	latestCount = loadLatestCounts()
+	fromHistory, err := latestHistoryCounts()
+	if err != nil {
+		slog.Error("Error reading latest counts from history", "error", err)
+	}
+	restored := 0
+	for channelID, count := range fromHistory {
+		if _, ok := latestCount[channelID]; !ok {
+			latestCount[channelID] = count
+			restored++
 		}
-		req.Header.Set("Content-Type", writer.FormDataContentType())
-		res, err := client.Do(req)
-		if err != nil {
-			fmt.Println(err)
-			return
+	}
+	if restored > 0 {
+		saveLatestCounts(latestCount)
+	}
+	slog.Info("Restored subscriber count baselines", "channels", len(latestCount), "from_history", restored)
+}
+
+func loadLatestCounts() map[string]int64 {
+	counts := make(map[string]int64)
+	data, err := os.ReadFile("latestCount.json")
+	if err != nil {
+		// Migrate the count written by single-channel versions
+		if legacy, err := os.ReadFile("latestCount.txt"); err == nil && currentConfig().ChannelID != "" {
+			if count, err := strconv.ParseInt(strings.TrimSpace(string(legacy)), 10, 64); err == nil {
+				counts[currentConfig().ChannelID] = count
+			}
 		}
-		defer res.Body.Close()
+		return counts
+	}
+	if err := json.Unmarshal(data, &counts); err != nil {
+		slog.Error("Error decoding latestCount.json", "error", err)
+	}
+	return counts
+}
+
+func saveLatestCounts(counts map[string]int64) {
+	data, _ := json.Marshal(counts)
+	if err := writeFileAtomic("latestCount.json", data, 0644); err != nil {
+		slog.Error("Error saving latestCount.json", "error", err)
 	}
 }
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// resetPollState forgets the counts and deliveries left behind by earlier tests
+func resetPollState(t *testing.T) {
+	t.Helper()
+	useTempDir(t)
+	latestCountMutex.Lock()
+	latestCount = nil
+	lastNotified = make(map[string]uint64)
+	lastNotifiedAt = make(map[string]time.Time)
+	latestCountMutex.Unlock()
+	deliveriesMutex.Lock()
+	deliveries, deliveriesNext, deliveriesFull = nil, 0, false
+	deliveriesMutex.Unlock()
+}
+
+// scriptedCounts answers every FetchChannelStats call with the next of counts
+func scriptedCounts(counts ...uint64) *fakeFetcher {
+	call := 0
+	return &fakeFetcher{stats: func(channels []ChannelConfig) (map[string]ChannelStats, error) {
+		count := counts[call]
+		call++
+		return map[string]ChannelStats{testChannelID: {ChannelID: testChannelID, SubscriberCount: count}}, nil
+	}}
+}
+
+// webhookCounts returns the subscriber_count of every webhook delivery, oldest first
+func webhookCounts(t *testing.T) []uint64 {
+	t.Helper()
+	deliveriesMutex.Lock()
+	recent := recentDeliveries()
+	deliveriesMutex.Unlock()
+
+	var counts []uint64
+	for i := len(recent) - 1; i >= 0; i-- {
+		if recent[i].Platform != "webhook" {
+			continue
+		}
+		var payload struct {
+			SubscriberCount uint64 `json:"subscriber_count"`
+		}
+		if err := json.Unmarshal([]byte(recent[i].Payload), &payload); err != nil {
+			t.Fatalf("decoding webhook payload %q: %v", recent[i].Payload, err)
+		}
+		counts = append(counts, payload.SubscriberCount)
+	}
+	return counts
+}
+
+func TestPollOnceNotifiesScriptedCounts(t *testing.T) {
+	resetPollState(t)
+	useConfig(t, testConfigYAML)
+
+	connect := connectTo(scriptedCounts(100, 100, 105, 103))
+	backoff := &pollBackoff{}
+	for i := 0; i < 4; i++ {
+		if _, err := pollOnce(context.Background(), backoff, connect); err != nil {
+			t.Fatalf("poll %d: %v", i, err)
+		}
+	}
+
+	// The first poll seeds the baseline and an unchanged count stays silent
+	got := webhookCounts(t)
+	if want := []uint64{105, 103}; !slices.Equal(got, want) {
+		t.Errorf("webhook notifications for %v, want %v", got, want)
+	}
+	if latestCount[testChannelID] != 103 {
+		t.Errorf("latest count is %d, want 103", latestCount[testChannelID])
+	}
+}
+
+func TestPollOnceReportsFetchErrors(t *testing.T) {
+	resetPollState(t)
+	useConfig(t, testConfigYAML)
+
+	failure := errors.New("youtube unavailable")
+	fetcher := &fakeFetcher{stats: func([]ChannelConfig) (map[string]ChannelStats, error) { return nil, failure }}
+	backoff := &pollBackoff{}
+	if _, err := pollOnce(context.Background(), backoff, connectTo(fetcher)); !errors.Is(err, failure) {
+		t.Fatalf("pollOnce returned %v, want %v", err, failure)
+	}
+	if backoff.failures != 1 {
+		t.Errorf("backoff counted %d failures, want 1", backoff.failures)
+	}
+}
+
+func TestPollRecoveredTurnsPanicIntoError(t *testing.T) {
+	resetPollState(t)
+	useConfig(t, testConfigYAML)
+
+	fetcher := &fakeFetcher{stats: func([]ChannelConfig) (map[string]ChannelStats, error) { panic("malformed response") }}
+	backoff := &pollBackoff{}
+	_, err := pollRecovered(context.Background(), backoff, connectTo(fetcher))
+	if err == nil || !strings.Contains(err.Error(), "malformed response") {
+		t.Fatalf("pollRecovered returned %v, want the panic as error", err)
+	}
+	if backoff.failures != 1 {
+		t.Errorf("backoff counted %d failures after the panic, want 1", backoff.failures)
+	}
+
+	// The loop keeps working once the fetcher behaves again
+	if _, err := pollRecovered(context.Background(), backoff, connectTo(scriptedCounts(100))); err != nil {
+		t.Fatalf("poll after the panic: %v", err)
+	}
+	if backoff.failures != 0 {
+		t.Errorf("backoff counted %d failures after recovering, want 0", backoff.failures)
+	}
+}
+
+func TestHandleHomeLinksLoginOnlyForOAuth(t *testing.T) {
+	tests := []struct {
+		name           string
+		yaml           string
+		serviceAccount bool
+		login          bool
+	}{
+		{name: "oauth", yaml: "client_id: id\nclient_secret: secret\nredirect_url: http://localhost:8080/oauth2callback\nchannel_id: UCxxxxxxxxxxxxxxxxxxxxxx\nwebhook_url: https://example.com/hook\n", login: true},
+		{name: "api key", yaml: testConfigYAML, login: false},
+		{name: "service account", yaml: "service_account_file: key.json\nchannel_id: UCxxxxxxxxxxxxxxxxxxxxxx\nwebhook_url: https://example.com/hook\n", serviceAccount: true, login: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, tt.yaml)
+			if tt.serviceAccount {
+				serviceAccount = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test"})
+				t.Cleanup(func() { serviceAccount = nil })
+			}
+			rec := httptest.NewRecorder()
+			handleHome(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if got := strings.Contains(rec.Body.String(), `href="/login"`); got != tt.login {
+				t.Errorf("home page links /login: %v, want %v\n%s", got, tt.login, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), `href="/dashboard"`) {
+				t.Errorf("home page does not link the dashboard\n%s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestUpdateSubscriberCountSendsWithoutLock(t *testing.T) {
+	resetPollState(t)
+	received := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		<-release
+	}))
+	defer server.Close()
+	cfg := useConfig(t, `
+api_key: test
+channel_id: UCxxxxxxxxxxxxxxxxxxxxxx
+webhook_url: `+server.URL+`
+`)
+	useHTTPClient(t, cfg)
+
+	updateSubscriberCount(context.Background(), cfg.Channels[0], 100)
+	done := make(chan struct{})
+	go func() {
+		updateSubscriberCount(context.Background(), cfg.Channels[0], 105)
+		close(done)
+	}()
+
+	<-received
+	if !latestCountMutex.TryLock() {
+		t.Error("latestCountMutex is held while the webhook is being sent")
+	} else {
+		latestCountMutex.Unlock()
+	}
+	close(release)
+	<-done
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+const (
+	// Number of recent uploads inspected on every poll
+	uploadsPageSize = 10
+	// Further pages are only read while the last seen video was not found, up to this many
+	maxUploadPages = 3
+)
+
+var (
+	latestVideo      map[string]string
+	latestVideoMutex sync.Mutex
+)
+
+// checkUploads notifies about videos published to the uploads playlist since the last poll.
+// The first poll for a channel only records the newest video as the baseline. Each check costs
+// a playlistItems.list call, so it only runs with notify_uploads.
+func checkUploads(ctx context.Context, fetcher StatsFetcher, channel ChannelConfig, playlistID string) {
+	if !currentConfig().NotifyUploads || playlistID == "" {
+		return
+	}
+
+	latestVideoMutex.Lock()
+	if latestVideo == nil {
+		latestVideo = loadLatestVideos()
+	}
+	lastSeen, ok := latestVideo[channel.ChannelID]
+	latestVideoMutex.Unlock()
+
+	// Not holding the lock while fetching lets channels be checked concurrently
+	items, err := fetchRecentUploads(ctx, fetcher, playlistID, lastSeen)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching uploads", "event", "poll", "channel_id", channel.ChannelID, "error", err)
+		return
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	latestVideoMutex.Lock()
+	defer latestVideoMutex.Unlock()
+
+	newest := items[0]
+	if !ok {
+		slog.InfoContext(ctx, "Seeding latest video", "event", "upload", "channel_id", channel.ChannelID, "video_id", videoID(newest))
+		latestVideo[channel.ChannelID] = videoID(newest)
+		saveLatestVideos(latestVideo)
+		return
+	}
+	if videoID(newest) == lastSeen {
+		return
+	}
+
+	var fresh []*youtube.PlaylistItem
+	for _, item := range items {
+		if videoID(item) == lastSeen {
+			break
+		}
+		fresh = append(fresh, item)
+	}
+	// The last seen video is gone (deleted or made private), only announce the newest one
+	if len(fresh) == len(items) {
+		fresh = fresh[:1]
+	}
+
+	latestVideo[channel.ChannelID] = videoID(newest)
+	saveLatestVideos(latestVideo)
+
+	// Announce oldest first
+	for i := len(fresh) - 1; i >= 0; i-- {
+		sendNewVideoNotification(ctx, channel, fresh[i])
+	}
+}
+
+// fetchRecentUploads returns the latest uploads sorted newest first by publish time. More pages
+// are read while lastSeen is set but not found yet, so a burst of uploads is not cut short.
+func fetchRecentUploads(ctx context.Context, fetcher StatsFetcher, playlistID, lastSeen string) ([]*youtube.PlaylistItem, error) {
+	var items []*youtube.PlaylistItem
+	pageToken := ""
+	for page := 0; page < maxUploadPages; page++ {
+		batch, next, err := fetcher.FetchUploads(ctx, playlistID, pageToken)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, batch...)
+		if lastSeen == "" || next == "" || slices.ContainsFunc(batch, func(item *youtube.PlaylistItem) bool { return videoID(item) == lastSeen }) {
+			break
+		}
+		pageToken = next
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return publishedAt(items[i]).After(publishedAt(items[j]))
+	})
+	return items, nil
+}
+
+// publishedAt is the zero time when the item has no valid timestamp, sorting it last
+func publishedAt(item *youtube.PlaylistItem) time.Time {
+	if item.Snippet == nil {
+		return time.Time{}
+	}
+	t, _ := time.Parse(time.RFC3339, item.Snippet.PublishedAt)
+	return t
+}
+
+// uploadsPlaylistID returns the playlist holding the uploads of the channel. Without the
+// contentDetails part it is derived from the channel ID, the UC prefix becomes UU.
+func uploadsPlaylistID(channel *youtube.Channel) string {
+	if channel.ContentDetails != nil && channel.ContentDetails.RelatedPlaylists != nil {
+		return channel.ContentDetails.RelatedPlaylists.Uploads
+	}
+	if strings.HasPrefix(channel.Id, "UC") {
+		return "UU" + strings.TrimPrefix(channel.Id, "UC")
+	}
+	return ""
+}
+
+func videoID(item *youtube.PlaylistItem) string {
+	if item.Snippet == nil || item.Snippet.ResourceId == nil {
+		return ""
+	}
+	return item.Snippet.ResourceId.VideoId
+}
+
+func videoURL(item *youtube.PlaylistItem) string {
+	return "https://www.youtube.com/watch?v=" + videoID(item)
+}
+
+func videoThumbnail(item *youtube.PlaylistItem) string {
+	thumbnails := item.Snippet.Thumbnails
+	if thumbnails == nil {
+		return ""
+	}
+	for _, thumbnail := range []*youtube.Thumbnail{thumbnails.Maxres, thumbnails.High, thumbnails.Medium, thumbnails.Default} {
+		if thumbnail != nil {
+			return thumbnail.Url
+		}
+	}
+	return ""
+}
+
+// sendNewVideoNotification announces video on every platform configured for channel
+func sendNewVideoNotification(ctx context.Context, channel ChannelConfig, video *youtube.PlaylistItem) {
+	slog.InfoContext(ctx, "New video", "event", "upload", "channel_id", channel.ChannelID, "video_id", videoID(video))
+
+	thumbnail := videoThumbnail(video)
+	markdown := fmt.Sprintf("New video from *%s*: [%s](%s)",
+		escapeMarkdownV2(channel.Name()), escapeMarkdownV2(video.Snippet.Title), escapeMarkdownV2URL(videoURL(video)))
+	if thumbnail != "" {
+		markdown += "\n" + escapeMarkdownV2(thumbnail)
+	}
+	sendChannelMessage(ctx, channelMessage{
+		ChannelID: channel.ChannelID,
+		Type:      "new_video",
+		Title:     fmt.Sprintf("New video from %s", channel.Name()),
+		Text:      video.Snippet.Title + "\n" + videoURL(video),
+		Markdown:  markdown,
+		URL:       videoURL(video),
+		Image:     thumbnail,
+	})
+}
+
+func loadLatestVideos() map[string]string {
+	videos := make(map[string]string)
+	data, err := os.ReadFile("latestVideo.json")
+	if err != nil {
+		return videos
+	}
+	if err := json.Unmarshal(data, &videos); err != nil {
+		slog.Error("Error decoding latestVideo.json", "error", err)
+	}
+	return videos
+}
+
+func saveLatestVideos(videos map[string]string) {
+	data, _ := json.Marshal(videos)
+	if err := writeFileAtomic("latestVideo.json", data, 0644); err != nil {
+		slog.Error("Error saving latestVideo.json", "error", err)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// upload is a playlist item for video id published at publishedAt
+func upload(id, publishedAt string) *youtube.PlaylistItem {
+	return &youtube.PlaylistItem{Snippet: &youtube.PlaylistItemSnippet{
+		PublishedAt: publishedAt,
+		ResourceId:  &youtube.ResourceId{VideoId: id},
+	}}
+}
+
+func uploadIDs(items []*youtube.PlaylistItem) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = videoID(item)
+	}
+	return ids
+}
+
+// uploadPages is a playlist whose pages are not in publish order, as after a scheduled premiere
+func uploadPages() [][]*youtube.PlaylistItem {
+	return [][]*youtube.PlaylistItem{
+		{upload("c", "2024-05-03T10:00:00Z"), upload("e", "2024-05-05T10:00:00Z"), upload("nodate", "")},
+		{upload("b", "2024-05-02T10:00:00Z"), upload("f", "2024-05-06T10:00:00Z")},
+		{upload("a", "2024-05-01T10:00:00Z"), upload("d", "2024-05-04T10:00:00Z")},
+		{upload("old", "2024-04-01T10:00:00Z")},
+	}
+}
+
+func TestFetchRecentUploads(t *testing.T) {
+	tests := []struct {
+		name     string
+		lastSeen string
+		want     []string
+		calls    int
+	}{
+		{name: "first run reads one page", lastSeen: "", want: []string{"e", "c", "nodate"}, calls: 1},
+		{name: "last seen on first page", lastSeen: "c", want: []string{"e", "c", "nodate"}, calls: 1},
+		{name: "last seen on later page", lastSeen: "b", want: []string{"f", "e", "c", "b", "nodate"}, calls: 2},
+		{name: "last seen beyond the page limit", lastSeen: "old", want: []string{"f", "e", "d", "c", "b", "a", "nodate"}, calls: maxUploadPages},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := &fakeFetcher{uploads: uploadPages()}
+			items, err := fetchRecentUploads(context.Background(), fetcher, "UUxxxxxxxxxxxxxxxxxxxxxx", tt.lastSeen)
+			if err != nil {
+				t.Fatalf("fetchRecentUploads: %v", err)
+			}
+			if got := uploadIDs(items); !slices.Equal(got, tt.want) {
+				t.Errorf("got uploads %v, want %v", got, tt.want)
+			}
+			if fetcher.uploadCalls != tt.calls {
+				t.Errorf("read %d pages, want %d", fetcher.uploadCalls, tt.calls)
+			}
+		})
+	}
+}
+
+// resetLatestVideos forgets the videos seen by earlier tests
+func resetLatestVideos(t *testing.T) {
+	t.Helper()
+	latestVideoMutex.Lock()
+	latestVideo = nil
+	latestVideoMutex.Unlock()
+	t.Cleanup(func() {
+		latestVideoMutex.Lock()
+		latestVideo = nil
+		latestVideoMutex.Unlock()
+	})
+}
+
+func TestCheckUploadsNeedsNotifyUploads(t *testing.T) {
+	resetPollState(t)
+	resetLatestVideos(t)
+	cfg := useConfig(t, testConfigYAML)
+
+	fetcher := &fakeFetcher{uploads: uploadPages()}
+	checkUploads(context.Background(), fetcher, cfg.Channels[0], "UUxxxxxxxxxxxxxxxxxxxxxx")
+	if fetcher.uploadCalls != 0 {
+		t.Errorf("uploads were read %d times without notify_uploads", fetcher.uploadCalls)
+	}
+}
+
+func TestCheckUploadsNotifiesWebhooks(t *testing.T) {
+	resetPollState(t)
+	resetLatestVideos(t)
+	cfg := useConfig(t, testConfigYAML+"notify_uploads: true\n")
+	channel := cfg.Channels[0]
+
+	fetcher := &fakeFetcher{uploads: [][]*youtube.PlaylistItem{{upload("a", "2024-05-01T10:00:00Z")}}}
+	checkUploads(context.Background(), fetcher, channel, "UUxxxxxxxxxxxxxxxxxxxxxx")
+	fetcher.uploads = [][]*youtube.PlaylistItem{{upload("b", "2024-05-02T10:00:00Z"), upload("a", "2024-05-01T10:00:00Z")}}
+	checkUploads(context.Background(), fetcher, channel, "UUxxxxxxxxxxxxxxxxxxxxxx")
+
+	deliveriesMutex.Lock()
+	recent := recentDeliveries()
+	deliveriesMutex.Unlock()
+	var announced []string
+	for _, d := range recent {
+		if d.Platform != "webhook" {
+			continue
+		}
+		var payload struct {
+			Type      string `json:"type"`
+			ChannelID string `json:"channel_id"`
+			URL       string `json:"url"`
+		}
+		if err := json.Unmarshal([]byte(d.Payload), &payload); err != nil {
+			t.Fatalf("decoding webhook payload %q: %v", d.Payload, err)
+		}
+		if payload.Type != "new_video" || payload.ChannelID != testChannelID {
+			t.Errorf("webhook payload %s, want a new_video message of %s", d.Payload, testChannelID)
+		}
+		announced = append(announced, payload.URL)
+	}
+	if want := []string{"https://www.youtube.com/watch?v=b"}; !slices.Equal(announced, want) {
+		t.Errorf("webhooks announced %v, want %v", announced, want)
+	}
+}
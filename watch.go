@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Last seen views, videos and comments per channel, subscribers are tracked in latestCount
+var (
+	latestMetrics      map[string]map[string]uint64
+	latestMetricsMutex sync.Mutex
+)
+
+func watchingMetric(metric string) bool {
+	return slices.Contains(currentConfig().WatchMetrics, metric)
+}
+
+// updateMetric notifies when a watched metric other than subscribers changes.
+// Like subscribers, the first value seen for a channel only seeds the baseline.
+func updateMetric(ctx context.Context, channel ChannelConfig, metric string, value uint64) {
+	latestMetricsMutex.Lock()
+	defer latestMetricsMutex.Unlock()
+
+	if latestMetrics == nil {
+		latestMetrics = loadLatestMetrics()
+	}
+	if latestMetrics[channel.ChannelID] == nil {
+		latestMetrics[channel.ChannelID] = make(map[string]uint64)
+	}
+
+	previous, known := latestMetrics[channel.ChannelID][metric]
+	if known && previous == value {
+		return
+	}
+	latestMetrics[channel.ChannelID][metric] = value
+	saveLatestMetrics(latestMetrics)
+
+	if !known {
+		slog.InfoContext(ctx, "Seeding metric", "event", "poll", "channel_id", channel.ChannelID, "metric", metric, "value", value)
+		return
+	}
+	dispatchNotification(ctx, newMetricNotification(channel, metric, previous, value))
+}
+
+func loadLatestMetrics() map[string]map[string]uint64 {
+	metrics := make(map[string]map[string]uint64)
+	data, err := os.ReadFile("latestMetrics.json")
+	if err != nil {
+		return metrics
+	}
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		slog.Error("Error decoding latestMetrics.json", "error", err)
+	}
+	return metrics
+}
+
+func saveLatestMetrics(metrics map[string]map[string]uint64) {
+	data, _ := json.Marshal(metrics)
+	if err := writeFileAtomic("latestMetrics.json", data, 0644); err != nil {
+		slog.Error("Error saving latestMetrics.json", "error", err)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// webhookSignatureHeader carries the HMAC of the body when webhook_secret is set
+const webhookSignatureHeader = "X-Signature-256"
+
+// Number of webhooks posted to concurrently
+const webhookWorkers = 4
+
+// The same change rendered again within this period gets the same event_id
+const eventIDBucket = time.Minute
+
+func sendWebhookNotification(ctx context.Context, n Notification) {
+	slog.InfoContext(ctx, "Sending webhook notification", "event", "notify", "platform", "webhook", "channel_id", n.ChannelID, "subscriber_count", n.Count)
+
+	body, err := webhookPayload(currentConfig().webhookPayloadTemplate, newWebhookPayloadData(n, n.Message()))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error rendering webhook payload", "event", "notify", "platform", "webhook", "channel_id", n.ChannelID, "error", err)
+		return
+	}
+	postWebhooks(ctx, webhooksFor(n.ChannelID), body)
+}
+
+// sendWebhookMessage posts m to the webhooks of its channel. The payload is fixed JSON, the
+// type field tells it apart from count changes rendered by webhook_payload_template.
+func sendWebhookMessage(ctx context.Context, m channelMessage) {
+	slog.InfoContext(ctx, "Sending webhook message", "event", "notify", "platform", "webhook", "channel_id", m.ChannelID, "type", m.Type)
+
+	now := time.Now().UTC()
+	payload := map[string]interface{}{
+		"type":     m.Type,
+		"title":    m.Title,
+		"message":  m.Text,
+		"event_id": messageEventID(m, now),
+		"sent_at":  now.Format(time.RFC3339),
+	}
+	if m.ChannelID != "" {
+		payload["channel_id"] = m.ChannelID
+	}
+	if m.URL != "" {
+		payload["url"] = m.URL
+	}
+	if pollID := pollIDFrom(ctx); pollID != "" {
+		payload["poll_id"] = pollID
+	}
+	body, _ := json.Marshal(payload)
+	postWebhooks(ctx, webhooksFor(m.ChannelID), body)
+}
+
+// postWebhooks posts body to every webhook concurrently. Retryable failures are queued with
+// webhook_durable.
+func postWebhooks(ctx context.Context, webhooks []WebhookConfig, body []byte) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, webhookWorkers)
+	for _, webhook := range webhooks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(webhook WebhookConfig) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := postWebhook(ctx, webhook, body); err != nil {
+				slog.ErrorContext(ctx, "Error sending webhook notification", "event", "notify", "platform", "webhook", "url", webhook.URL, "error", err)
+				if currentConfig().WebhookDurable && retryableError(err) {
+					enqueueWebhook(ctx, webhook, body)
+				}
+				return
+			}
+			slog.InfoContext(ctx, "Webhook notification delivered", "event", "notify", "platform", "webhook", "url", webhook.URL)
+		}(webhook)
+	}
+	wg.Wait()
+}
+
+// webhookPayloadData is passed to webhook_payload_template
+type webhookPayloadData struct {
+	ChannelID     string
+	ChannelTitle  string
+	Metric        string
+	Count         uint64
+	PreviousCount uint64
+	Delta         int64
+	Message       string
+	Timestamp     time.Time
+	// EventID lets receivers drop duplicate deliveries of the same change
+	EventID string
+	PollID  string
+}
+
+func newWebhookPayloadData(n Notification, message string) webhookPayloadData {
+	now := time.Now().UTC()
+	return webhookPayloadData{
+		ChannelID:     n.ChannelID,
+		ChannelTitle:  n.ChannelTitle,
+		Metric:        n.Metric,
+		Count:         n.Count,
+		PreviousCount: n.PreviousCount,
+		Delta:         n.Delta,
+		Message:       message,
+		Timestamp:     now,
+		EventID:       eventID(n, now),
+		PollID:        n.PollID,
+	}
+}
+
+// eventID hashes what identifies a change: the channel, the metric, the counts and the time
+// truncated to eventIDBucket
+func eventID(n Notification, t time.Time) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%d|%d", n.ChannelID, n.Metric, n.PreviousCount, n.Count, t.Truncate(eventIDBucket).Unix())))
+	return hex.EncodeToString(sum[:16])
+}
+
+// messageEventID is eventID for a channelMessage, it hashes the type and text instead of counts
+func messageEventID(m channelMessage, t time.Time) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%d", m.ChannelID, m.Type, m.Text, t.Truncate(eventIDBucket).Unix())))
+	return hex.EncodeToString(sum[:16])
+}
+
+// webhookPayloadFuncs are available in webhook_payload_template, json encodes any value so
+// strings are quoted and escaped
+var webhookPayloadFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"humanize": formatCount,
+}
+
+// webhookPayload renders the request body, tmpl is nil for the default JSON payload
+func webhookPayload(tmpl *template.Template, data webhookPayloadData) ([]byte, error) {
+	if tmpl == nil {
+		payload := map[string]interface{}{
+			"channel_id":       data.ChannelID,
+			"channel_title":    data.ChannelTitle,
+			"metric":           data.Metric,
+			"subscriber_count": data.Count,
+			"previous_count":   data.PreviousCount,
+			"delta":            data.Delta,
+			"message":          data.Message,
+			"event_id":         data.EventID,
+			"sent_at":          data.Timestamp.Format(time.RFC3339),
+		}
+		if data.PollID != "" {
+			payload["poll_id"] = data.PollID
+		}
+		return json.Marshal(payload)
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// parseWebhookPayloadTemplate parses webhook_payload_template and checks that it renders valid
+// JSON for a sample notification. An empty text keeps the default payload.
+func parseWebhookPayloadTemplate(text string, messageTemplate *template.Template) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("webhook").Funcs(webhookPayloadFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := Notification{ChannelID: "UC0000000000000000000000", ChannelTitle: "Sample \"channel\"", Metric: metricSubscribers, Count: 1000, PreviousCount: 990, Delta: 10}
+	var message strings.Builder
+	if err := messageTemplate.Execute(&message, sample); err != nil {
+		return nil, err
+	}
+	body, err := webhookPayload(tmpl, newWebhookPayloadData(sample, message.String()))
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("sample payload is not valid JSON: %s", body)
+	}
+	return tmpl, nil
+}
+
+func postWebhook(ctx context.Context, webhook WebhookConfig, body []byte) (err error) {
+	defer func() { recordPollDelivery(pollIDFrom(ctx), "webhook", webhook.URL, string(body), err) }()
+	if dryRun(ctx, "webhook", webhook.URL, string(body)) {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	contentType := webhook.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range webhook.Headers {
+		req.Header.Set(key, value)
+	}
+	if currentConfig().WebhookSecret != "" {
+		req.Header.Set(webhookSignatureHeader, signWebhookBody(body, currentConfig().WebhookSecret))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return withKind(ErrTransient, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return &statusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// signWebhookBody returns the X-Signature-256 value for body, in the same format as GitHub:
+// "sha256=" followed by the lowercase hex HMAC-SHA256 of the exact raw request body bytes,
+// keyed with webhook_secret. Receivers should compute it over the body as received, before
+// any JSON parsing, and compare with hmac.Equal.
+func signWebhookBody(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Failed deliveries are given up after this long unless webhook_max_age is set
+	defaultWebhookMaxAge = 24 * time.Hour
+	// How often the queue is checked for deliveries due for another attempt
+	webhookQueueInterval = 10 * time.Second
+	webhookRetryInitial  = 30 * time.Second
+	webhookRetryMax      = time.Hour
+)
+
+// queuedWebhook is a failed webhook delivery waiting in webhookQueue.json
+type queuedWebhook struct {
+	ID          string        `json:"id"`
+	Webhook     WebhookConfig `json:"webhook"`
+	Body        []byte        `json:"body"`
+	Attempts    int           `json:"attempts"`
+	FirstFailed time.Time     `json:"first_failed"`
+	NextAttempt time.Time     `json:"next_attempt"`
+	// PollID is the poll cycle that caused the delivery, empty for other deliveries
+	PollID string `json:"poll_id,omitempty"`
+}
+
+var (
+	webhookQueue      []queuedWebhook
+	webhookQueueMutex sync.Mutex
+	webhookQueueSeq   int64
+)
+
+// enqueueWebhook persists a failed delivery so runWebhookQueue retries it, even after a restart
+func enqueueWebhook(ctx context.Context, webhook WebhookConfig, body []byte) {
+	webhookQueueMutex.Lock()
+	defer webhookQueueMutex.Unlock()
+	if webhookQueue == nil {
+		webhookQueue = loadWebhookQueue()
+	}
+
+	now := time.Now()
+	webhookQueueSeq++
+	webhookQueue = append(webhookQueue, queuedWebhook{
+		ID:          strconv.FormatInt(now.UnixNano(), 36) + "-" + strconv.FormatInt(webhookQueueSeq, 36),
+		Webhook:     webhook,
+		Body:        body,
+		Attempts:    1,
+		FirstFailed: now,
+		NextAttempt: now.Add(webhookRetryInitial),
+		PollID:      pollIDFrom(ctx),
+	})
+	saveWebhookQueue(webhookQueue)
+	slog.WarnContext(ctx, "Queued webhook notification for retry", "event", "notify", "platform", "webhook", "url", webhook.URL, "queued", len(webhookQueue))
+}
+
+// runWebhookQueue retries queued deliveries with exponential backoff until they succeed or are
+// older than webhook_max_age
+func runWebhookQueue(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(webhookQueueInterval):
+		}
+		retryQueuedWebhooks()
+	}
+}
+
+func retryQueuedWebhooks() {
+	webhookQueueMutex.Lock()
+	if webhookQueue == nil {
+		webhookQueue = loadWebhookQueue()
+	}
+	now := time.Now()
+	var due []queuedWebhook
+	for _, entry := range webhookQueue {
+		if !entry.NextAttempt.After(now) {
+			due = append(due, entry)
+		}
+	}
+	webhookQueueMutex.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	// Deliveries are posted without holding the lock, new failures may be queued meanwhile
+	done := make(map[string]bool)
+	retried := make(map[string]queuedWebhook)
+	maxAge := currentConfig().webhookMaxAge
+	for _, entry := range due {
+		ctx := withPollID(context.Background(), entry.PollID)
+		err := postWebhook(ctx, entry.Webhook, entry.Body)
+		if err == nil {
+			slog.InfoContext(ctx, "Queued webhook notification delivered", "event", "notify", "platform", "webhook", "url", entry.Webhook.URL, "attempts", entry.Attempts+1)
+			done[entry.ID] = true
+			continue
+		}
+		if time.Since(entry.FirstFailed) > maxAge {
+			slog.ErrorContext(ctx, "Giving up on queued webhook notification", "event", "notify", "platform", "webhook", "url", entry.Webhook.URL, "attempts", entry.Attempts+1, "error", err)
+			done[entry.ID] = true
+			continue
+		}
+
+		entry.Attempts++
+		delay := webhookRetryInitial << min(entry.Attempts-1, 10)
+		entry.NextAttempt = time.Now().Add(min(delay, webhookRetryMax))
+		slog.WarnContext(ctx, "Queued webhook notification failed again", "event", "notify", "platform", "webhook", "url", entry.Webhook.URL, "attempts", entry.Attempts, "next_attempt", entry.NextAttempt, "error", err)
+		retried[entry.ID] = entry
+	}
+
+	webhookQueueMutex.Lock()
+	defer webhookQueueMutex.Unlock()
+	kept := webhookQueue[:0]
+	for _, entry := range webhookQueue {
+		if done[entry.ID] {
+			continue
+		}
+		if updated, ok := retried[entry.ID]; ok {
+			entry = updated
+		}
+		kept = append(kept, entry)
+	}
+	webhookQueue = kept
+	saveWebhookQueue(webhookQueue)
+}
+
+// queuedWebhookCount is the number of deliveries waiting for a retry
+func queuedWebhookCount() int {
+	webhookQueueMutex.Lock()
+	defer webhookQueueMutex.Unlock()
+	return len(webhookQueue)
+}
+
+func loadWebhookQueue() []queuedWebhook {
+	queue := []queuedWebhook{}
+	data, err := os.ReadFile("webhookQueue.json")
+	if err != nil {
+		return queue
+	}
+	if err := json.Unmarshal(data, &queue); err != nil {
+		slog.Error("Error decoding webhookQueue.json", "error", err)
+	}
+	return queue
+}
+
+// saveWebhookQueue keeps the file private since queued webhooks may carry auth headers
+func saveWebhookQueue(queue []queuedWebhook) {
+	data, _ := json.Marshal(queue)
+	if err := writeFileAtomic("webhookQueue.json", data, 0600); err != nil {
+		slog.Error("Error saving webhookQueue.json", "error", err)
+	}
+}
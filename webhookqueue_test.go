@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyReceiver is a webhook endpoint answering 503 until it is brought online
+type flakyReceiver struct {
+	mu       sync.Mutex
+	online   bool
+	attempts int
+	bodies   []string
+}
+
+func (f *flakyReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if !f.online {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	f.bodies = append(f.bodies, string(body))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *flakyReceiver) setOnline(online bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.online = online
+}
+
+// counts returns the requests received and the deliveries accepted
+func (f *flakyReceiver) counts() (attempts, delivered int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.attempts, len(f.bodies)
+}
+
+// resetWebhookQueue starts the test with an empty queue read from the working directory
+func resetWebhookQueue(t *testing.T) {
+	t.Helper()
+	webhookQueueMutex.Lock()
+	webhookQueue = nil
+	webhookQueueMutex.Unlock()
+	t.Cleanup(func() {
+		webhookQueueMutex.Lock()
+		webhookQueue = nil
+		webhookQueueMutex.Unlock()
+	})
+}
+
+// makeQueueDue moves every queued delivery's next attempt to now
+func makeQueueDue() {
+	webhookQueueMutex.Lock()
+	defer webhookQueueMutex.Unlock()
+	for i := range webhookQueue {
+		webhookQueue[i].NextAttempt = time.Now()
+	}
+}
+
+func TestRetryQueuedWebhooksDeliversOnceReceiverIsBack(t *testing.T) {
+	useTempDir(t)
+	resetWebhookQueue(t)
+	receiver := &flakyReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	useConfig(t, `
+api_key: test
+channel_id: UCxxxxxxxxxxxxxxxxxxxxxx
+webhook_url: `+server.URL+`
+webhook_durable: true
+`)
+
+	sendWebhookNotification(context.Background(), Notification{ChannelID: testChannelID, Count: 105, PreviousCount: 100, Delta: 5})
+	if n := queuedWebhookCount(); n != 1 {
+		t.Fatalf("%d deliveries queued after the receiver failed, want 1", n)
+	}
+
+	// Not due yet, the receiver is left alone
+	retryQueuedWebhooks()
+	if attempts, _ := receiver.counts(); attempts != 1 {
+		t.Fatalf("receiver got %d attempts before the retry was due, want 1", attempts)
+	}
+
+	// Due but still offline, the delivery stays queued with one more attempt
+	makeQueueDue()
+	retryQueuedWebhooks()
+	webhookQueueMutex.Lock()
+	if len(webhookQueue) != 1 || webhookQueue[0].Attempts != 2 || !webhookQueue[0].NextAttempt.After(time.Now()) {
+		t.Errorf("%d deliveries queued after a failed retry, want one with 2 attempts due later", len(webhookQueue))
+	}
+	webhookQueueMutex.Unlock()
+
+	// The queue survives a restart
+	resetWebhookQueue(t)
+	if n := queuedWebhookCount(); n != 0 {
+		t.Fatalf("%d deliveries queued after the reset, want 0", n)
+	}
+	retryQueuedWebhooks()
+	if n := queuedWebhookCount(); n != 1 {
+		t.Fatalf("%d deliveries reloaded from webhookQueue.json, want 1", n)
+	}
+
+	receiver.setOnline(true)
+	makeQueueDue()
+	retryQueuedWebhooks()
+
+	if n := queuedWebhookCount(); n != 0 {
+		t.Errorf("%d deliveries still queued after the receiver came back, want 0", n)
+	}
+	if attempts, delivered := receiver.counts(); attempts != 3 || delivered != 1 {
+		t.Errorf("receiver got %d attempts and accepted %d deliveries, want 3 and 1", attempts, delivered)
+	}
+	if reloaded := loadWebhookQueue(); len(reloaded) != 0 {
+		t.Errorf("webhookQueue.json still holds %d deliveries", len(reloaded))
+	}
+}
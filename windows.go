@@ -0,0 +1,96 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// deltaWindow is an entry of delta_windows, such as 24h or 7d
+type deltaWindow struct {
+	label    string
+	duration time.Duration
+}
+
+// WindowDelta is the subscriber change over one of the delta_windows, available to
+// message_template as .Windows. Available is false when the history has no count from around
+// the start of the window, for example because the app was not running then.
+type WindowDelta struct {
+	Window    string
+	Delta     int64
+	Available bool
+}
+
+func (w WindowDelta) String() string {
+	if !w.Available {
+		return w.Window + ": not enough history"
+	}
+	return fmt.Sprintf("%s in %s", formatDelta(w.Delta), w.Window)
+}
+
+// WindowSummary lists the window deltas, e.g. "+12 in 24h, +340 in 7d"
+func (n Notification) WindowSummary() string {
+	parts := make([]string, len(n.Windows))
+	for i, w := range n.Windows {
+		parts[i] = w.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseDeltaWindows parses delta_windows, which accept Go durations plus a d suffix for days
+func parseDeltaWindows(windows []string) ([]deltaWindow, error) {
+	parsed := make([]deltaWindow, 0, len(windows))
+	for _, window := range windows {
+		var d time.Duration
+		var err error
+		if days, ok := strings.CutSuffix(window, "d"); ok {
+			var n int
+			n, err = strconv.Atoi(days)
+			d = time.Duration(n) * 24 * time.Hour
+		} else {
+			d, err = time.ParseDuration(window)
+		}
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid window %q, expected a positive duration such as 1h or 7d", window)
+		}
+		parsed = append(parsed, deltaWindow{label: window, duration: d})
+	}
+	return parsed, nil
+}
+
+// subscriberWindows computes the change of channelID to count over every delta window from the
+// history database. A window is only available when a count was recorded close to its start,
+// within a tenth of the window or two poll intervals, whichever is longer.
+func subscriberWindows(channelID string, count uint64) []WindowDelta {
+	windows := currentConfig().deltaWindows
+	if historyDB == nil || len(windows) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	deltas := make([]WindowDelta, len(windows))
+	for i, window := range windows {
+		deltas[i].Window = window.label
+		start := now.Add(-window.duration)
+		var timestamp, previous int64
+		err := historyDB.QueryRow(`SELECT timestamp, subscriber_count FROM history
+			WHERE channel_id = ? AND timestamp <= ? ORDER BY timestamp DESC LIMIT 1`, channelID, start.Unix()).Scan(&timestamp, &previous)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			slog.Error("Error reading history", "channel_id", channelID, "window", window.label, "error", err)
+			continue
+		}
+		if start.Sub(time.Unix(timestamp, 0)) > max(window.duration/10, 2*pollInterval()) {
+			continue
+		}
+		deltas[i].Delta = countDelta(uint64(previous), count)
+		deltas[i].Available = true
+	}
+	return deltas
+}